@@ -0,0 +1,152 @@
+// Package bench measures the session middleware with different stores. It is used by the
+// benchmarks of this package and by the load generator in bench/loadgen.
+package bench
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+)
+
+// Stores returns the manager options of the store setups to compare, by name.
+func Stores() map[string]func() []session.Option {
+	return map[string]func() []session.Option{
+		"memory": func() []session.Option {
+			return []session.Option{session.WithStore(session.NewInMemorySessionStore())}
+		},
+		"memory-1-shard": func() []session.Option {
+			return []session.Option{session.WithStore(session.NewInMemorySessionStore(session.WithShards(1)))}
+		},
+		"encrypted": func() []session.Option {
+			return []session.Option{session.WithStore(session.NewEncryptedStore(session.NewInMemorySessionStore(), newAEAD()))}
+		},
+		"compressed": func() []session.Option {
+			return []session.Option{
+				session.WithStore(session.NewInMemorySessionStore()),
+				session.WithCompression(session.Gzip, 256),
+			}
+		},
+	}
+}
+
+func newAEAD() cipher.AEAD {
+	key := make([]byte, 32)
+	if _, err := cryptorand.Read(key); err != nil {
+		panic(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+// Config describes a load test.
+type Config struct {
+	// Concurrency is the number of clients sending requests at the same time.
+	Concurrency int
+	// Requests is the total number of requests.
+	Requests int
+	// ReuseRatio is the fraction of requests sending the cookie of an earlier response of the client.
+	ReuseRatio float64
+	// PayloadSize is the number of bytes each request puts into its session.
+	PayloadSize int
+	// Options configure the manager, e.g. one of Stores.
+	Options []session.Option
+}
+
+// Result summarizes a load test.
+type Result struct {
+	Requests         int
+	Duration         time.Duration
+	P50, P99         time.Duration
+	AllocsPerRequest float64
+}
+
+// NewRouter returns a router whose handler puts payloadSize bytes into the session of every request.
+func NewRouter(sm *session.SessionManager, payloadSize int) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	payload := strings.Repeat("x", payloadSize)
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		session.GetSession(c).Put("payload", payload)
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+// Run sends the requests of cfg to a router served in-process and measures their latency.
+func Run(cfg Config) Result {
+	sm := session.NewSessionManager(cfg.Options...)
+	defer sm.Close()
+	router := NewRouter(sm, cfg.PayloadSize)
+	concurrency := max(cfg.Concurrency, 1)
+
+	latencies := make([][]time.Duration, concurrency)
+	var wg sync.WaitGroup
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for worker := range concurrency {
+		n := cfg.Requests / concurrency
+		if worker < cfg.Requests%concurrency {
+			n++
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			latencies[worker] = client(router, n, cfg.ReuseRatio)
+		}()
+	}
+	wg.Wait()
+	duration := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	all := slices.Concat(latencies...)
+	slices.Sort(all)
+	result := Result{Requests: len(all), Duration: duration}
+	if len(all) > 0 {
+		result.P50 = all[len(all)*50/100]
+		result.P99 = all[len(all)*99/100]
+		result.AllocsPerRequest = float64(after.Mallocs-before.Mallocs) / float64(len(all))
+	}
+	return result
+}
+
+// client sends n requests, reusing the cookie of an earlier response for the given ratio of them.
+func client(router http.Handler, n int, reuseRatio float64) []time.Duration {
+	latencies := make([]time.Duration, 0, n)
+	var cookies []*http.Cookie
+	for range n {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if len(cookies) > 0 && rand.Float64() < reuseRatio {
+			req.AddCookie(cookies[rand.IntN(len(cookies))])
+		}
+		rw := httptest.NewRecorder()
+
+		start := time.Now()
+		router.ServeHTTP(rw, req)
+		latencies = append(latencies, time.Since(start))
+
+		if set := rw.Result().Cookies(); len(set) > 0 {
+			cookies = append(cookies, set[0])
+		}
+	}
+	return latencies
+}
@@ -0,0 +1,62 @@
+package bench
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+)
+
+func BenchmarkStores(b *testing.B) {
+	stores := Stores()
+	names := make([]string, 0, len(stores))
+	for name := range stores {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		for _, payload := range []int{64, 4096} {
+			b.Run(fmt.Sprintf("%s/%dB", name, payload), func(b *testing.B) {
+				sm := session.NewSessionManager(stores[name]()...)
+				defer sm.Close()
+				router := NewRouter(sm, payload)
+
+				// Every client keeps its session, as a logged in user does.
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					var cookie *http.Cookie
+					for pb.Next() {
+						req := httptest.NewRequest(http.MethodGet, "/", nil)
+						if cookie != nil {
+							req.AddCookie(cookie)
+						}
+						rw := httptest.NewRecorder()
+						router.ServeHTTP(rw, req)
+						if cookies := rw.Result().Cookies(); len(cookies) > 0 {
+							cookie = cookies[0]
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	result := Run(Config{
+		Concurrency: 4,
+		Requests:    101,
+		ReuseRatio:  0.5,
+		PayloadSize: 16,
+		Options:     Stores()["memory"](),
+	})
+	assert.Equal(t, 101, result.Requests)
+	assert.Positive(t, result.P50)
+	assert.GreaterOrEqual(t, result.P99, result.P50)
+	assert.Positive(t, result.AllocsPerRequest)
+}
@@ -0,0 +1,50 @@
+// Command loadgen runs a load test against the session middleware for each store of bench.Stores
+// and prints the latency percentiles and allocations per request.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/zetr0nix/gin-memory-sessions-go/bench"
+)
+
+func main() {
+	concurrency := flag.Int("concurrency", 16, "number of concurrent clients")
+	requests := flag.Int("requests", 100000, "total number of requests per store")
+	reuse := flag.Float64("reuse", 0.9, "fraction of requests reusing an earlier session of the client")
+	payload := flag.Int("payload", 256, "bytes put into the session by every request")
+	store := flag.String("store", "", "only test this store")
+	flag.Parse()
+
+	stores := bench.Stores()
+	names := make([]string, 0, len(stores))
+	for name := range stores {
+		if *store == "" || name == *store {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		log.Fatalf("unknown store %q", *store)
+	}
+	slices.Sort(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "store\trequests\treq/s\tp50\tp99\tallocs/req")
+	for _, name := range names {
+		result := bench.Run(bench.Config{
+			Concurrency: *concurrency,
+			Requests:    *requests,
+			ReuseRatio:  *reuse,
+			PayloadSize: *payload,
+			Options:     stores[name](),
+		})
+		fmt.Fprintf(w, "%s\t%d\t%.0f\t%s\t%s\t%.1f\n", name, result.Requests,
+			float64(result.Requests)/result.Duration.Seconds(), result.P50, result.P99, result.AllocsPerRequest)
+	}
+	w.Flush()
+}
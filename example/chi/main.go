@@ -0,0 +1,66 @@
+// Command chi serves sessions to a Chi router. Chi routers are plain net/http, so Middleware is used
+// as is and handlers read the session with session.FromContext.
+//
+// To keep the module free of the chi dependency, the router is declared as the subset of chi.Router
+// used here and backed by http.ServeMux. With chi, pass chi.NewRouter() to routes instead.
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+)
+
+// router is the part of chi.Router the example uses.
+type router interface {
+	http.Handler
+	Use(middlewares ...func(http.Handler) http.Handler)
+	Get(pattern string, handler http.HandlerFunc)
+}
+
+func routes(r router, sm *session.SessionManager) {
+	r.Use(sm.Middleware)
+	r.Get("/test", func(w http.ResponseWriter, req *http.Request) {
+		sess, _ := session.FromContext(req.Context())
+		count, _ := session.GetGenericValue[int](sess, "count")
+		count++
+		sess.Put("count", count)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(count)
+	})
+}
+
+// mux is a minimal router with the methods of chi.Router that routes uses.
+type mux struct {
+	http.ServeMux
+	middlewares []func(http.Handler) http.Handler
+}
+
+func (m *mux) Use(middlewares ...func(http.Handler) http.Handler) {
+	m.middlewares = append(m.middlewares, middlewares...)
+}
+
+func (m *mux) Get(pattern string, handler http.HandlerFunc) {
+	m.ServeMux.Handle("GET "+pattern, handler)
+}
+
+func (m *mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var handler http.Handler = &m.ServeMux
+	for i := len(m.middlewares) - 1; i >= 0; i-- {
+		handler = m.middlewares[i](handler)
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func main() {
+	sm := session.NewSessionManager()
+	defer sm.Close()
+	r := &mux{}
+	routes(r, sm)
+
+	if err := http.ListenAndServe(":4200", r); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+)
+
+func TestRoutes(t *testing.T) {
+	sm := session.NewSessionManager()
+	t.Cleanup(func() { sm.Close() })
+	r := &mux{}
+	routes(r, sm)
+
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, "1\n", rw.Body.String())
+	cookies := rw.Result().Cookies()
+	assert.Len(t, cookies, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.AddCookie(cookies[0])
+	rw = httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+	assert.Equal(t, "2\n", rw.Body.String())
+}
@@ -7,6 +7,7 @@ import (
 
 func main() {
 	sm := session.NewSessionManager()
+	defer sm.Close()
 	r := gin.New()
 	ep := r.Group("", sm.Handle())
 
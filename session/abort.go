@@ -0,0 +1,53 @@
+package session
+
+// snapshot holds the state of a session at the start of a request, so the request's changes can be rolled back.
+type snapshot struct {
+	session *Session
+	data    map[string]any
+	keys    []string
+	fresh   bool
+	changed map[string]struct{}
+}
+
+// WithSaveOnAbort decides whether changes made by requests that panic or are aborted with c.Abort
+// are persisted. Defaults to true; with false the session is restored to its state at the start of the request.
+func WithSaveOnAbort(save bool) Option {
+	return func(s *SessionManager) {
+		s.saveOnAbort = save
+	}
+}
+
+// takeSnapshot returns nil unless failed requests are rolled back.
+func (m *SessionManager) takeSnapshot(session *Session) *snapshot {
+	if m.saveOnAbort {
+		return nil
+	}
+
+	session.resolve()
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	sn := &snapshot{
+		session: session,
+		data:    session.snapshot(),
+		keys:    append([]string(nil), session.keys...),
+		fresh:   session.fresh,
+		changed: make(map[string]struct{}, len(session.changed)),
+	}
+	for k := range session.changed {
+		sn.changed[k] = struct{}{}
+	}
+	return sn
+}
+
+// rollback discards the changes of the request and attaches the session it started with again.
+func (m *SessionManager) rollback(state *requestState, sn *snapshot) {
+	sn.session.mu.Lock()
+	sn.session.setData(sn.data)
+	sn.session.keys = sn.keys
+	sn.session.fresh = sn.fresh
+	sn.session.changed = sn.changed
+	sn.session.mu.Unlock()
+
+	state.set(sn.session)
+}
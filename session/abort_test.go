@@ -0,0 +1,57 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveOnAbort(t *testing.T) {
+	tests := []struct {
+		name        string
+		saveOnAbort bool
+		fail        func(c *gin.Context)
+		want        any
+	}{
+		{"abort saved", true, func(c *gin.Context) { c.AbortWithStatus(http.StatusBadRequest) }, "changed"},
+		{"abort rolled back", false, func(c *gin.Context) { c.AbortWithStatus(http.StatusBadRequest) }, "initial"},
+		{"panic saved", true, func(c *gin.Context) { panic("handler failed") }, "changed"},
+		{"panic rolled back", false, func(c *gin.Context) { panic("handler failed") }, "initial"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewInMemorySessionStore()
+			sm := NewSessionManager(WithStore(store), WithSaveOnAbort(tt.saveOnAbort))
+			router := gin.New()
+			router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}))
+			router.Use(sm.Handle())
+			router.GET("/init", func(c *gin.Context) {
+				GetSession(c).Put("key", "initial")
+			})
+			router.GET("/fail", func(c *gin.Context) {
+				GetSession(c).Put("key", "changed")
+				GetSession(c).Put("other", "changed")
+				tt.fail(c)
+			})
+
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/init", nil))
+			cookie := rw.Result().Cookies()[0]
+
+			req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+			req.AddCookie(cookie)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			sess := store.read(cookie.Value)
+			assert.Equal(t, tt.want, sess.Get("key"))
+			if tt.want == "initial" {
+				assert.Nil(t, sess.Get("other"))
+			}
+		})
+	}
+}
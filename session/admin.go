@@ -0,0 +1,83 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// counter is implemented by stores which can count their sessions.
+type counter interface {
+	count() int
+}
+
+// storeCount returns the number of sessions in store, or -1 if it cannot count them.
+func storeCount(store SessionStore) int {
+	if c, ok := store.(counter); ok {
+		return c.count()
+	}
+	return -1
+}
+
+// AdminRoutes registers JSON endpoints for operators on rg:
+//
+//	GET    /sessions                      {"sessions": n}, the number of stored sessions
+//	GET    /users/:user/sessions          the SessionInfo of the sessions of the user
+//	DELETE /users/:user/sessions          destroys every session of the user
+//	DELETE /users/:user/sessions/:hash    destroys the session of the user with the IDHash
+//
+// Each request must pass authorize, otherwise it is answered with 403 Forbidden. authorize is
+// required, as the endpoints allow taking over the session management of every user.
+func (m *SessionManager) AdminRoutes(rg *gin.RouterGroup, authorize func(c *gin.Context) bool) {
+	if authorize == nil {
+		panic("session: AdminRoutes requires an authorization check")
+	}
+	rg.Use(func(c *gin.Context) {
+		if !authorize(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": http.StatusText(http.StatusForbidden)})
+		}
+	})
+
+	rg.GET("/sessions", func(c *gin.Context) {
+		n := storeCount(m.store)
+		if n < 0 {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "the store cannot count its sessions"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"sessions": n})
+	})
+	rg.GET("/users/:user/sessions", func(c *gin.Context) {
+		infos, err := m.SessionsForUser(c.Request.Context(), c.Param("user"))
+		if err != nil {
+			adminError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, infos)
+	})
+	rg.DELETE("/users/:user/sessions", func(c *gin.Context) {
+		err := m.DestroyAllForUser(c.Request.Context(), c.Param("user"))
+		if err != nil {
+			adminError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	rg.DELETE("/users/:user/sessions/:hash", func(c *gin.Context) {
+		err := m.DestroyUserSession(c.Request.Context(), c.Param("user"), c.Param("hash"))
+		if err != nil {
+			adminError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+func adminError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrUnknownSession) {
+		status = http.StatusNotFound
+	}
+	_ = c.Error(err)
+	c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+	router, do := newUserRouter(t, sm)
+	sm.AdminRoutes(router.Group("/admin"), func(c *gin.Context) bool {
+		return c.GetHeader("Authorization") == "Bearer secret"
+	})
+	admin := func(method, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	laptop := do("/login/alice", nil)
+	do("/login/alice", nil)
+	bob := do("/login/bob", nil)
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/admin/sessions", nil))
+	assert.Equal(t, http.StatusForbidden, rw.Code)
+
+	rw = admin(http.MethodGet, "/admin/sessions")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.JSONEq(t, `{"sessions": 3}`, rw.Body.String())
+
+	rw = admin(http.MethodGet, "/admin/users/alice/sessions")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	var infos []SessionInfo
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &infos))
+	assert.Len(t, infos, 2)
+
+	rw = admin(http.MethodDelete, "/admin/users/alice/sessions/"+hashID(laptop.Value))
+	assert.Equal(t, http.StatusNoContent, rw.Code)
+	assert.Nil(t, store.read(laptop.Value))
+	rw = admin(http.MethodDelete, "/admin/users/alice/sessions/"+hashID(laptop.Value))
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+
+	rw = admin(http.MethodDelete, "/admin/users/alice/sessions")
+	assert.Equal(t, http.StatusNoContent, rw.Code)
+	assert.Equal(t, 1, store.count())
+	assert.NotNil(t, store.read(bob.Value))
+}
+
+func TestAdminRoutesRequireAuthorization(t *testing.T) {
+	sm := NewSessionManager()
+	t.Cleanup(func() { sm.Close() })
+
+	assert.Panics(t, func() { sm.AdminRoutes(gin.New().Group("/admin"), nil) })
+}
@@ -0,0 +1,214 @@
+package session
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// WithAsyncWrites makes saves return as soon as the session is queued. The queue is written to the
+// store in batches every flushInterval, trading a window of lost writes on a crash for lower request
+// latency with remote stores. A session saved several times within an interval is written once.
+// When queueSize sessions are queued, the saving request writes the batch itself. Close writes the
+// remaining queue. Errors while writing a batch, such as version conflicts, are logged.
+func WithAsyncWrites(queueSize int, flushInterval time.Duration) Option {
+	return func(s *SessionManager) {
+		s.asyncQueueSize = queueSize
+		s.asyncFlushInterval = flushInterval
+	}
+}
+
+// useAsyncWrites wraps the store for WithAsyncWrites.
+func (m *SessionManager) useAsyncWrites() {
+	if m.asyncQueueSize <= 0 || m.asyncFlushInterval <= 0 {
+		return
+	}
+	m.store = newAsyncStore(m.store, m.asyncQueueSize, m.asyncFlushInterval, m.logger)
+}
+
+type asyncStore struct {
+	inner     SessionStore
+	queueSize int
+	logger    Logger
+
+	mu sync.Mutex
+	// pending holds the queued sessions by store key, flushing the batch being written.
+	// Both are consulted by read, so a request sees the writes of the previous ones.
+	pending  map[string]*Session
+	flushing map[string]*Session
+	// stopped is set by Close, later writes go to the inner store directly.
+	stopped bool
+	// flushMu serializes the batch writes.
+	flushMu sync.Mutex
+
+	ticker    *time.Ticker
+	closed    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newAsyncStore(inner SessionStore, queueSize int, flushInterval time.Duration, logger Logger) *asyncStore {
+	s := &asyncStore{
+		inner:     inner,
+		queueSize: queueSize,
+		logger:    logger,
+		pending:   make(map[string]*Session),
+		ticker:    time.NewTicker(flushInterval),
+		closed:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *asyncStore) run() {
+	defer close(s.done)
+
+	for {
+		select {
+		case <-s.ticker.C:
+			s.flush()
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// flush writes the queued sessions to the inner store.
+func (s *asyncStore) flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[string]*Session)
+	s.flushing = batch
+	s.mu.Unlock()
+
+	for _, session := range batch {
+		err := s.inner.write(session)
+		if err != nil {
+			s.logger.Error("asynchronous session write failed", "error", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.flushing = nil
+	s.mu.Unlock()
+}
+
+func (s *asyncStore) read(id string) *Session {
+	s.mu.Lock()
+	session, ok := s.pending[id]
+	if !ok {
+		session, ok = s.flushing[id]
+	}
+	s.mu.Unlock()
+
+	if ok {
+		return session
+	}
+	return s.inner.read(id)
+}
+
+func (s *asyncStore) readChecked(id string) (*Session, error) {
+	s.mu.Lock()
+	session, ok := s.pending[id]
+	if !ok {
+		session, ok = s.flushing[id]
+	}
+	s.mu.Unlock()
+
+	if ok {
+		return session, nil
+	}
+	return readStore(s.inner, id)
+}
+
+func (s *asyncStore) write(session *Session) error {
+	s.mu.Lock()
+	_, queued := s.pending[session.storeKey()]
+	full := !queued && len(s.pending) >= s.queueSize
+	if !full && !s.stopped {
+		s.pending[session.storeKey()] = session
+	}
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	if stopped {
+		return s.inner.write(session)
+	}
+	if full {
+		s.flush()
+		return s.inner.write(session)
+	}
+	session.queue()
+	return nil
+}
+
+func (s *asyncStore) destroy(id string) error {
+	s.mu.Lock()
+	delete(s.pending, id)
+	s.mu.Unlock()
+
+	// A batch being written may still contain the session.
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	return s.inner.destroy(id)
+}
+
+// gc writes the queue first, so sessions saved since the last batch are not collected.
+func (s *asyncStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	s.flush()
+	return s.inner.gc(idleExpiration, absoluteExpiration, expired)
+}
+
+func (s *asyncStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	if store, ok := s.inner.(gcBudgeter); ok {
+		store.setGCBudget(maxDuration, maxDeletes)
+	}
+}
+
+// invalidate drops the queued session of key, which another instance destroyed.
+func (s *asyncStore) invalidate(key string) {
+	s.mu.Lock()
+	delete(s.pending, key)
+	_, flushing := s.flushing[key]
+	s.mu.Unlock()
+
+	if flushing {
+		// The batch being written brings the session back, remove it again afterwards.
+		s.flushMu.Lock()
+		defer s.flushMu.Unlock()
+		if err := s.inner.destroy(key); err != nil {
+			s.logger.Error("destroying invalidated session failed", "error", err)
+		}
+	}
+	invalidate(s.inner, key)
+}
+
+func (s *asyncStore) count() int {
+	return storeCount(s.inner)
+}
+
+func (s *asyncStore) unwrap() SessionStore {
+	return s.inner
+}
+
+// Close stops the background writes, writes the queue and closes the inner store if it is an io.Closer.
+func (s *asyncStore) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.ticker.Stop()
+		close(s.closed)
+		<-s.done
+		s.mu.Lock()
+		s.stopped = true
+		s.mu.Unlock()
+		s.flush()
+		if closer, ok := s.inner.(io.Closer); ok {
+			err = closer.Close()
+		}
+	})
+	return err
+}
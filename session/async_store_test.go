@@ -0,0 +1,110 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncWrites(t *testing.T) {
+	inner := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(inner), WithAsyncWrites(2, time.Hour))
+	store := sm.store.(*asyncStore)
+
+	sessions := make([]*Session, 3)
+	for i := range sessions {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		sess.Put("key", i)
+		sessions[i] = sess
+	}
+
+	assert.NoError(t, sm.save(sessions[0], nil))
+	assert.NoError(t, sm.save(sessions[0], nil))
+	assert.NoError(t, sm.save(sessions[1], nil))
+	assert.Nil(t, inner.read(sessions[0].id))
+	assert.Same(t, sessions[0], sm.read(sessions[0].id))
+
+	// The queue is full, so the request writes the batch and its session itself.
+	assert.NoError(t, sm.save(sessions[2], nil))
+	assert.Equal(t, 3, inner.count())
+	assert.Empty(t, store.pending)
+
+	assert.NoError(t, sm.save(sessions[1], nil))
+	assert.NoError(t, store.destroy(sessions[1].id))
+	assert.Nil(t, sm.read(sessions[1].id))
+	assert.Nil(t, inner.read(sessions[1].id))
+
+	sessions[0].Put("key", "changed")
+	assert.NoError(t, sm.save(sessions[0], nil))
+	assert.NoError(t, sm.Close())
+	assert.Equal(t, "changed", inner.read(sessions[0].id).Get("key"))
+}
+
+func TestAsyncWritesCreateOnce(t *testing.T) {
+	created := 0
+	sm := NewSessionManager(WithAsyncWrites(10, time.Hour), WithHooks(Hooks{
+		OnCreate: func(*Session) { created++ },
+	}))
+	t.Cleanup(func() { sm.Close() })
+
+	sess, err := sm.Create(context.Background())
+	assert.NoError(t, err)
+	sess.Put("key", "first")
+	assert.NoError(t, sm.Commit(context.Background(), sess))
+	loaded, err := sm.Load(context.Background(), sess.ID())
+	assert.NoError(t, err)
+	loaded.Put("key", "second")
+	assert.NoError(t, sm.Commit(context.Background(), loaded))
+	assert.Equal(t, 1, created)
+}
+
+// blockingStore blocks writes until release is closed.
+type blockingStore struct {
+	*inMemorySessionStore
+	release chan struct{}
+}
+
+func (s *blockingStore) write(session *Session) error {
+	<-s.release
+	return s.inMemorySessionStore.write(session)
+}
+
+func TestShutdown(t *testing.T) {
+	inner := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(inner), WithAsyncWrites(10, time.Hour), WithWriteCoalescing(time.Hour))
+	queued, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	queued.Put("key", "value")
+	assert.NoError(t, sm.save(queued, nil))
+	assert.Nil(t, inner.read(queued.id))
+
+	assert.NoError(t, sm.Shutdown(context.Background()))
+	assert.NotNil(t, inner.read(queued.id))
+
+	// A request finishing after the shutdown writes its session directly.
+	late, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	late.Put("key", "value")
+	assert.NoError(t, sm.save(late, nil))
+	assert.NotNil(t, inner.read(late.id))
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	inner := &blockingStore{inMemorySessionStore: NewInMemorySessionStore(), release: make(chan struct{})}
+	sm := NewSessionManager(WithStore(inner), WithAsyncWrites(10, time.Hour))
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+	assert.NoError(t, sm.save(sess, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, sm.Shutdown(ctx), context.DeadlineExceeded)
+
+	close(inner.release)
+	assert.NoError(t, sm.Close())
+	assert.NotNil(t, inner.read(sess.id))
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// AuditEventType names a security relevant session event.
+type AuditEventType string
+
+const (
+	AuditCreated           AuditEventType = "created"
+	AuditRegenerated       AuditEventType = "regenerated"
+	AuditDestroyed         AuditEventType = "destroyed"
+	AuditExpired           AuditEventType = "expired"
+	AuditInvalidID         AuditEventType = "rejected_invalid_id"
+	AuditIPMismatch        AuditEventType = "ip_mismatch"
+	AuditUserAgentMismatch AuditEventType = "user_agent_mismatch"
+	AuditImpersonateStart  AuditEventType = "impersonate_start"
+	AuditImpersonateStop   AuditEventType = "impersonate_stop"
+)
+
+// AuditEvent describes a session event for security monitoring. It never contains the session id itself.
+type AuditEvent struct {
+	Type AuditEventType
+	Time time.Time
+	// SessionIDHash is the hex encoded SHA-256 of the session id, empty for AuditInvalidID.
+	SessionIDHash string
+	// IP is the client IP of the request causing the event, or the IP the session was created from.
+	IP string
+	// UserAgent is the User-Agent of the request causing the event, or the one the session was created with.
+	UserAgent string
+	// UserID is the user the session is logged in as, nil for anonymous sessions.
+	UserID any
+	// ImpersonatorID is the user impersonating UserID, see SessionManager.Impersonate.
+	ImpersonatorID any
+}
+
+// AuditSink receives audit events, e.g. to forward them to a SIEM. Audit is called synchronously.
+type AuditSink interface {
+	Audit(event AuditEvent)
+}
+
+// AuditFunc adapts a function to an AuditSink.
+type AuditFunc func(event AuditEvent)
+
+func (f AuditFunc) Audit(event AuditEvent) {
+	f(event)
+}
+
+func WithAuditSink(sink AuditSink) Option {
+	return func(s *SessionManager) {
+		s.auditSink = sink
+	}
+}
+
+// audit sends an event to the audit sink. session and r may be nil.
+func (m *SessionManager) audit(typ AuditEventType, session *Session, r *http.Request) {
+	if m.auditSink == nil {
+		return
+	}
+	event := AuditEvent{
+		Type: typ,
+		Time: m.now(),
+	}
+	if session != nil {
+		event.SessionIDHash = hashID(session.id)
+		event.IP = session.ip
+		event.UserAgent = session.agent
+		event.UserID, _ = session.load(UserIDKey)
+		event.ImpersonatorID, _ = session.load(ImpersonatorIDKey)
+	}
+	if r != nil {
+		event.IP = m.clientIP(r).String()
+		event.UserAgent = r.UserAgent()
+	}
+	m.auditSink.Audit(event)
+}
+
+// hashID returns the hex encoded SHA-256 of a session id, which identifies a session without granting access.
+func hashID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
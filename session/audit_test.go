@@ -0,0 +1,60 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditSink(t *testing.T) {
+	var events []AuditEvent
+	sm := NewSessionManager(
+		WithBindToIP(true),
+		WithAuditSink(AuditFunc(func(event AuditEvent) { events = append(events, event) })),
+	)
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+	router.GET("/regenerate", func(c *gin.Context) {
+		_, err := sm.Regenerate(c)
+		assert.NoError(t, err)
+	})
+
+	do := func(path, remoteAddr string, cookie *http.Cookie) *http.Cookie {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("User-Agent", "test")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		return rw.Result().Cookies()[0]
+	}
+
+	cookie := do("/", "10.0.0.1:1", nil)
+	cookie = do("/regenerate", "10.0.0.1:1", cookie)
+	do("/", "10.0.0.1:1", &http.Cookie{Name: "session", Value: "guessed"})
+	do("/", "10.0.0.2:1", cookie)
+
+	var types []AuditEventType
+	for _, event := range events {
+		types = append(types, event.Type)
+		assert.Equal(t, "test", event.UserAgent)
+		assert.NotContains(t, event.SessionIDHash, cookie.Value)
+	}
+	assert.Equal(t, []AuditEventType{
+		AuditCreated, AuditRegenerated, AuditInvalidID, AuditCreated, AuditIPMismatch, AuditDestroyed, AuditCreated,
+	}, types)
+	assert.Equal(t, "10.0.0.1", events[0].IP)
+	sum := sha256.Sum256([]byte(cookie.Value))
+	assert.Equal(t, hex.EncodeToString(sum[:]), events[1].SessionIDHash)
+}
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserIDKey is the session key under which Login stores the user id.
+const UserIDKey = "session.user_id"
+
+// Login regenerates the session id and binds the session to userID, keeping the other session data.
+// The new cookie replaces the old one with the response, so a session id set by an attacker before
+// the login is never authenticated.
+func (m *SessionManager) Login(c *gin.Context, userID any) error {
+	err := m.LoginContext(c.Request.Context(), userID)
+	if err != nil {
+		return err
+	}
+	session, _ := SessionFrom(c)
+	m.mirror(c, session)
+	return nil
+}
+
+// LoginContext is Login for the request context of Middleware.
+func (m *SessionManager) LoginContext(ctx context.Context, userID any) error {
+	err := m.admitUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	_, err = m.RegenerateContext(ctx)
+	if err != nil {
+		return err
+	}
+	return m.BindUserContext(ctx, userID)
+}
+
+// WithAuthKeys names session keys that Logout removes in addition to UserIDKey,
+// e.g. roles or tokens stored after Login.
+func WithAuthKeys(keys ...string) Option {
+	return func(s *SessionManager) {
+		s.authKeys = keys
+	}
+}
+
+// Logout removes the auth keys from the session, destroys it and attaches a new anonymous session.
+// Requests still holding the old session no longer see the auth keys. The cookie is expired,
+// or replaced if the handler puts values into the new session.
+func (m *SessionManager) Logout(c *gin.Context) error {
+	err := m.LogoutContext(c.Request.Context())
+	if err != nil {
+		return err
+	}
+	session, _ := SessionFrom(c)
+	m.mirror(c, session)
+	return nil
+}
+
+// LogoutContext is Logout for the request context of Middleware.
+func (m *SessionManager) LogoutContext(ctx context.Context) error {
+	state := stateFrom(ctx)
+	session, ok := state.get()
+	if !ok {
+		return ErrSessionNotFound
+	}
+	err := m.forget(state)
+	if err != nil {
+		return err
+	}
+	m.unindex(session)
+	for _, key := range append([]string{UserIDKey, ImpersonatorIDKey}, m.authKeys...) {
+		session.Delete(key)
+	}
+	err = m.DestroyContext(ctx)
+	if err != nil {
+		return err
+	}
+	state.logout()
+	return nil
+}
+
+// PromoteFunc moves data of a guest session into the session of a user who just logged in.
+type PromoteFunc func(guest, authenticated *Session) error
+
+// PromoteSession logs the user in like Login, but starts with an empty session and lets merge decide
+// which pre-login data, e.g. cart contents or the locale, is carried over.
+func (m *SessionManager) PromoteSession(c *gin.Context, userID any, merge PromoteFunc) error {
+	err := m.PromoteSessionContext(c.Request.Context(), userID, merge)
+	if err != nil {
+		return err
+	}
+	session, _ := SessionFrom(c)
+	m.mirror(c, session)
+	return nil
+}
+
+// PromoteSessionContext is PromoteSession for the request context of Middleware.
+func (m *SessionManager) PromoteSessionContext(ctx context.Context, userID any, merge PromoteFunc) error {
+	guest, ok := FromContext(ctx)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	err := m.admitUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	session, err := m.RegenerateContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range session.Keys() {
+		session.Delete(key)
+	}
+	err = merge(guest, session)
+	if err != nil {
+		return err
+	}
+	return m.BindUserContext(ctx, userID)
+}
@@ -0,0 +1,135 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogin(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/cart", func(c *gin.Context) {
+		GetSession(c).Put("cart", "book")
+	})
+	router.GET("/login", func(c *gin.Context) {
+		assert.NoError(t, sm.Login(c, 42))
+		sess := GetSession(c)
+		assert.Equal(t, 42, sess.Get(UserIDKey))
+		c.String(http.StatusOK, "welcome")
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/cart", nil))
+	anonymous := rw.Result().Cookies()[0]
+
+	rw = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	req.AddCookie(anonymous)
+	router.ServeHTTP(rw, req)
+	cookies := rw.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.NotEqual(t, anonymous.Value, cookies[0].Value)
+
+	assert.Nil(t, store.read(anonymous.Value))
+	sess := store.read(cookies[0].Value)
+	assert.Equal(t, "book", sess.Get("cart"))
+	assert.Equal(t, 42, sess.Get(UserIDKey))
+}
+
+func TestLogout(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store), WithAuthKeys("role"))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/login", func(c *gin.Context) {
+		assert.NoError(t, sm.Login(c, 42))
+		GetSession(c).Put("role", "admin")
+	})
+	router.GET("/logout", func(c *gin.Context) {
+		assert.NoError(t, sm.Logout(c))
+		assert.Nil(t, GetSession(c).Get(UserIDKey))
+	})
+	router.GET("/logout-flash", func(c *gin.Context) {
+		assert.NoError(t, sm.Logout(c))
+		GetSession(c).Put("flash", "bye")
+	})
+
+	login := func() *http.Cookie {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/login", nil))
+		return rw.Result().Cookies()[0]
+	}
+	do := func(path string, cookie *http.Cookie) *http.Cookie {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(cookie)
+		router.ServeHTTP(rw, req)
+		cookies := rw.Result().Cookies()
+		assert.Len(t, cookies, 1)
+		return cookies[0]
+	}
+
+	cookie := login()
+	old := store.read(cookie.Value)
+	expired := do("/logout", cookie)
+	assert.Equal(t, "", expired.Value)
+	assert.Less(t, expired.MaxAge, 0)
+	assert.Nil(t, store.read(cookie.Value))
+	assert.Nil(t, old.Get(UserIDKey))
+	assert.Nil(t, old.Get("role"))
+
+	cookie = login()
+	replaced := do("/logout-flash", cookie)
+	assert.NotEqual(t, cookie.Value, replaced.Value)
+	assert.Greater(t, replaced.MaxAge, 0)
+	assert.Equal(t, "bye", store.read(replaced.Value).Get("flash"))
+}
+
+func TestPromoteSession(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/guest", func(c *gin.Context) {
+		sess := GetSession(c)
+		sess.Put("cart", []string{"book"})
+		sess.Put("locale", "de")
+		sess.Put("tracking", "abc")
+	})
+	router.GET("/login", func(c *gin.Context) {
+		err := sm.PromoteSession(c, 42, func(guest, authenticated *Session) error {
+			for _, key := range []string{"cart", "locale"} {
+				authenticated.Put(key, guest.Get(key))
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/guest", nil))
+	guest := rw.Result().Cookies()[0]
+
+	rw = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	req.AddCookie(guest)
+	router.ServeHTTP(rw, req)
+	cookie := rw.Result().Cookies()[0]
+	assert.NotEqual(t, guest.Value, cookie.Value)
+	assert.Nil(t, store.read(guest.Value))
+
+	sess := store.read(cookie.Value)
+	assert.Equal(t, []string{"book"}, sess.Get("cart"))
+	assert.Equal(t, "de", sess.Get("locale"))
+	assert.Nil(t, sess.Get("tracking"))
+	assert.Equal(t, 42, sess.Get(UserIDKey))
+}
@@ -0,0 +1,162 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// WithBindToIP binds sessions to the client IP they were created from. A session used from
+// another address is destroyed and replaced by a new one, so a stolen cookie is useless elsewhere.
+func WithBindToIP(bind bool) Option {
+	return func(s *SessionManager) {
+		s.bindToIP = bind
+	}
+}
+
+// WithBindToUserAgent binds sessions to a fingerprint of the User-Agent they were created with.
+// A session used with another User-Agent is destroyed and replaced by a new one.
+func WithBindToUserAgent(bind bool) Option {
+	return func(s *SessionManager) {
+		s.bindToUserAgent = bind
+	}
+}
+
+// WithTrustedProxies sets the proxies whose X-Forwarded-For header is trusted, as CIDRs or single addresses.
+// Without trusted proxies the client IP is the remote address of the connection. It panics on invalid input.
+func WithTrustedProxies(cidrs ...string) Option {
+	return func(s *SessionManager) {
+		s.trustedProxies = nil
+		for _, cidr := range cidrs {
+			if !strings.Contains(cidr, "/") {
+				ip, err := netip.ParseAddr(cidr)
+				if err != nil {
+					panic(err)
+				}
+				s.trustedProxies = append(s.trustedProxies, netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
+				continue
+			}
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				panic(err)
+			}
+			s.trustedProxies = append(s.trustedProxies, prefix.Masked())
+		}
+	}
+}
+
+// WithIPTolerance accepts addresses in the same network as the bound IP, e.g. 24 and 64 bits
+// for clients moving within an IPv4 /24 or an IPv6 /64. The default is an exact match.
+func WithIPTolerance(ipv4Bits, ipv6Bits int) Option {
+	return func(s *SessionManager) {
+		s.ipv4Bits = ipv4Bits
+		s.ipv6Bits = ipv6Bits
+	}
+}
+
+// clientIP returns the address of the client. X-Forwarded-For is only followed
+// while the hop it was received from is a trusted proxy.
+func (m *SessionManager) clientIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	ip = ip.Unmap()
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0 && m.trustedProxy(ip); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		ip = hop.Unmap()
+	}
+	return ip
+}
+
+func (m *SessionManager) trustedProxy(ip netip.Addr) bool {
+	for _, prefix := range m.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that created the session, taking WithTrustedProxies into account.
+// It is empty for sessions not created by a request.
+func (s *Session) ClientIP() string {
+	return s.ip
+}
+
+// UserAgent returns the User-Agent of the client that created the session.
+// It is empty for sessions not created by a request.
+func (s *Session) UserAgent() string {
+	return s.agent
+}
+
+// bind records the client of r in session where nothing was recorded yet.
+func (m *SessionManager) bind(r *http.Request, session *Session) {
+	if session.ip == "" {
+		if ip := m.clientIP(r); ip.IsValid() {
+			session.ip = ip.String()
+		}
+	}
+	if session.agent == "" {
+		session.agent = r.UserAgent()
+	}
+	if m.bindToUserAgent && session.userAgent == "" {
+		session.userAgent = fingerprint(r.UserAgent())
+	}
+}
+
+// checkBinding reports whether session may be used by the client of r.
+// Sessions used by another client are destroyed.
+func (m *SessionManager) checkBinding(r *http.Request, session *Session) bool {
+	if m.bindToIP && session.ip != "" && !m.sameNetwork(session.ip, m.clientIP(r)) {
+		m.audit(AuditIPMismatch, session, r)
+	} else if m.bindToUserAgent && session.userAgent != "" && session.userAgent != fingerprint(r.UserAgent()) {
+		m.audit(AuditUserAgentMismatch, session, r)
+	} else {
+		// sessions created before binding was enabled are bound now
+		m.bind(r, session)
+		return true
+	}
+
+	m.logSession("session rejected, client binding mismatch", session)
+	if err := m.store.destroy(session.storeKey()); err != nil {
+		m.logger.Error("destroying session failed", "error", err)
+	}
+	m.unindex(session)
+	m.hooks.destroy(session)
+	m.audit(AuditDestroyed, session, r)
+	return false
+}
+
+func (m *SessionManager) sameNetwork(bound string, ip netip.Addr) bool {
+	boundIP, err := netip.ParseAddr(bound)
+	if err != nil || !ip.IsValid() {
+		return false
+	}
+	bits := m.ipv6Bits
+	if boundIP.Is4() {
+		bits = m.ipv4Bits
+	}
+	if bits <= 0 || bits >= boundIP.BitLen() {
+		return boundIP == ip
+	}
+	prefix, err := boundIP.Prefix(bits)
+	return err == nil && prefix.Contains(ip)
+}
+
+func fingerprint(userAgent string) string {
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:])
+}
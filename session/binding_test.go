@@ -0,0 +1,116 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindToIP(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		from  string
+		valid bool
+	}{
+		{"same ip", nil, "10.0.0.1:2000", true},
+		{"other ip", nil, "10.0.0.2:1234", false},
+		{"tolerated subnet", []Option{WithIPTolerance(24, 64)}, "10.0.0.2:1234", true},
+		{"outside subnet", []Option{WithIPTolerance(24, 64)}, "10.0.1.1:1234", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewInMemorySessionStore()
+			sm := NewSessionManager(append([]Option{WithStore(store), WithBindToIP(true)}, tt.opts...)...)
+			t.Cleanup(func() { sm.Close() })
+			router := gin.New()
+			router.Use(sm.Handle())
+			router.GET("/", func(c *gin.Context) {
+				sess := GetSession(c)
+				count, _ := GetGenericValue[int](sess, "count")
+				sess.Put("count", count+1)
+			})
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			router.ServeHTTP(rw, req)
+			cookie := rw.Result().Cookies()[0]
+
+			rw = httptest.NewRecorder()
+			req = httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.from
+			req.AddCookie(cookie)
+			router.ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.valid, rw.Result().Cookies()[0].Value == cookie.Value)
+			if !tt.valid {
+				assert.Nil(t, store.read(cookie.Value))
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	sm := NewSessionManager()
+	t.Cleanup(func() { sm.Close() })
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.168.0.10:1234"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 5.6.7.8")
+	assert.Equal(t, "192.168.0.10", sm.clientIP(req).String())
+
+	WithTrustedProxies("192.168.0.0/16", "5.6.7.8")(sm)
+	assert.Equal(t, "1.2.3.4", sm.clientIP(req).String())
+
+	WithTrustedProxies("192.168.0.0/16")(sm)
+	assert.Equal(t, "5.6.7.8", sm.clientIP(req).String())
+
+	assert.Panics(t, func() { NewSessionManager(WithTrustedProxies("proxy")) })
+}
+
+func TestSessionClientIP(t *testing.T) {
+	sm := NewSessionManager(WithTrustedProxies("10.0.0.0/8"))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, GetSession(c).ClientIP())
+	})
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.1.2.3:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, "203.0.113.7", rw.Body.String())
+}
+
+func TestBindToUserAgent(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store), WithBindToUserAgent(true))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+
+	do := func(userAgent string, cookie *http.Cookie) *http.Cookie {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("User-Agent", userAgent)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		return rw.Result().Cookies()[0]
+	}
+
+	cookie := do("Firefox", nil)
+	assert.Equal(t, cookie.Value, do("Firefox", cookie).Value)
+	assert.NotEqual(t, cookie.Value, do("curl", cookie).Value)
+	assert.Nil(t, store.read(cookie.Value))
+}
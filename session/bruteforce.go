@@ -0,0 +1,92 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrTooManyInvalidIDs is returned for requests whose client sent more unknown or invalid
+// session ids than allowed by WithInvalidIDLimit. The middleware responds with 429 Too Many Requests.
+var ErrTooManyInvalidIDs = errors.New("too many invalid session ids")
+
+// WithInvalidIDLimit rejects requests carrying an unknown or invalid session id once their client IP
+// sent more than limit of them within window, to slow down cookie guessing. Requests with valid
+// sessions are not affected. Hooks.OnInvalidID can be used for alerting instead of or in addition to this.
+func WithInvalidIDLimit(limit int, window time.Duration) Option {
+	return func(s *SessionManager) {
+		s.invalidIDLimit = limit
+		s.invalidIDWindow = window
+	}
+}
+
+// attemptTracker counts events per client IP in fixed windows.
+type attemptTracker struct {
+	mu       sync.Mutex
+	window   time.Duration
+	now      func() time.Time
+	attempts map[string]*attempts
+}
+
+type attempts struct {
+	count int
+	start time.Time
+}
+
+func newAttemptTracker(window time.Duration, now func() time.Time) *attemptTracker {
+	return &attemptTracker{
+		window:   window,
+		now:      now,
+		attempts: make(map[string]*attempts),
+	}
+}
+
+// add records an attempt of ip and returns the number of attempts in the current window.
+func (t *attemptTracker) add(ip string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a, ok := t.attempts[ip]
+	if !ok || t.now().Sub(a.start) > t.window {
+		a = &attempts{start: t.now()}
+		t.attempts[ip] = a
+	}
+	a.count++
+	return a.count
+}
+
+// prune forgets the IPs whose window has passed.
+func (t *attemptTracker) prune() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for ip, a := range t.attempts {
+		if t.now().Sub(a.start) > t.window {
+			delete(t.attempts, ip)
+		}
+	}
+}
+
+// rejectInvalidID records that r carried an unknown or invalid session id.
+func (m *SessionManager) rejectInvalidID(r *http.Request) error {
+	m.audit(AuditInvalidID, nil, r)
+	if m.invalidIDs == nil {
+		return nil
+	}
+	ip := m.clientIP(r).String()
+	count := m.invalidIDs.add(ip)
+	m.hooks.invalidID(ip, count)
+	if m.invalidIDLimit > 0 && count > m.invalidIDLimit {
+		return ErrTooManyInvalidIDs
+	}
+	return nil
+}
+
+// errorStatus is the status code of requests that could not start a session because of err.
+func errorStatus(err error) int {
+	if errors.Is(err, ErrTooManyInvalidIDs) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
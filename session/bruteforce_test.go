@@ -0,0 +1,51 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidIDLimit(t *testing.T) {
+	var alerts []int
+	sm := NewSessionManager(
+		WithInvalidIDLimit(2, time.Minute),
+		WithHooks(Hooks{OnInvalidID: func(ip string, attempts int) {
+			assert.Equal(t, "10.0.0.1", ip)
+			alerts = append(alerts, attempts)
+		}}),
+	)
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+
+	do := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	valid := do(nil).Result().Cookies()[0]
+	probe := &http.Cookie{Name: "session", Value: "guessed"}
+	assert.Equal(t, http.StatusOK, do(probe).Code)
+	assert.Equal(t, http.StatusOK, do(probe).Code)
+	assert.Equal(t, http.StatusTooManyRequests, do(probe).Code)
+	assert.Equal(t, http.StatusOK, do(valid).Code)
+	assert.Equal(t, []int{1, 2, 3}, alerts)
+
+	sm.invalidIDs.window = 0
+	sm.invalidIDs.prune()
+	assert.Equal(t, http.StatusOK, do(probe).Code)
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// cacheVersionPrefix prefixes the session keys holding the versions of values put by PutCached.
+const cacheVersionPrefix = "cache.version."
+
+// PutCached stores value under key as a cached copy of data owned elsewhere, e.g. the roles of the user.
+// version identifies the state of the source, e.g. a counter incremented on every role change.
+func (s *Session) PutCached(key string, value any, version int64) {
+	s.Put(key, value)
+	s.Put(cacheVersionPrefix+key, strconv.FormatInt(version, 10))
+}
+
+// GetCached returns the value of PutCached if it was cached with at least minVersion
+// and not invalidated by SessionManager.InvalidateCachedKey since.
+func (s *Session) GetCached(key string, minVersion int64) (any, bool) {
+	stored, ok := s.load(cacheVersionPrefix + key)
+	if !ok {
+		return nil, false
+	}
+	text, ok := stored.(string)
+	if !ok {
+		return nil, false
+	}
+	version, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || version < minVersion {
+		return nil, false
+	}
+	return s.load(key)
+}
+
+// InvalidateCachedKey removes the value cached by PutCached under key from every session of the user,
+// e.g. after their roles changed. If ctx belongs to a request of the user, its session is updated as well.
+func (m *SessionManager) InvalidateCachedKey(ctx context.Context, userID any, key string) error {
+	keys, err := m.userIndex.UserSessions(userKey(userID))
+	if err != nil {
+		return err
+	}
+	current, _ := FromContext(ctx)
+	if current != nil {
+		if owner, ok := current.load(UserIDKey); !ok || userKey(owner) != userKey(userID) {
+			current = nil
+		}
+	}
+	if current != nil {
+		current.invalidateCached(key)
+	}
+
+	for _, storeKey := range keys {
+		if current != nil && current.storeKey() == storeKey {
+			continue
+		}
+		session, err := m.readKey(storeKey, "")
+		if err != nil && !errors.Is(err, ErrCorruptSession) {
+			return err
+		}
+		if session == nil || !session.invalidateCached(key) {
+			continue
+		}
+		err = m.save(session, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalidateCached deletes a value of PutCached and reports whether the session held it.
+func (s *Session) invalidateCached(key string) bool {
+	if _, ok := s.load(cacheVersionPrefix + key); !ok {
+		return false
+	}
+	s.Delete(key)
+	s.Delete(cacheVersionPrefix + key)
+	return true
+}
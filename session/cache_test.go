@@ -0,0 +1,74 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedValues(t *testing.T) {
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.PutCached("roles", "admin", 2)
+	roles, ok := sess.GetCached("roles", 2)
+	assert.True(t, ok)
+	assert.Equal(t, "admin", roles)
+	_, ok = sess.GetCached("roles", 3)
+	assert.False(t, ok)
+	_, ok = sess.GetCached("plan", 0)
+	assert.False(t, ok)
+
+	sess.Put(cacheVersionPrefix+"plan", 3.0)
+	_, ok = sess.GetCached("plan", 0)
+	assert.False(t, ok)
+}
+
+func TestInvalidateCachedKey(t *testing.T) {
+	sm := NewSessionManager()
+	t.Cleanup(func() { sm.Close() })
+	router, do := newUserRouter(t, sm)
+	router.GET("/cache", func(c *gin.Context) {
+		GetSession(c).PutCached("roles", "editor", 1)
+	})
+	router.GET("/roles", func(c *gin.Context) {
+		roles, _ := GetSession(c).GetCached("roles", 1)
+		c.JSON(http.StatusOK, roles)
+	})
+	router.GET("/revoke", func(c *gin.Context) {
+		assert.NoError(t, sm.InvalidateCachedKey(c.Request.Context(), "alice", "roles"))
+		_, ok := GetSession(c).GetCached("roles", 1)
+		assert.False(t, ok)
+	})
+	router.GET("/revoke/bob", func(c *gin.Context) {
+		assert.NoError(t, sm.InvalidateCachedKey(c.Request.Context(), "bob", "roles"))
+	})
+	roles := func(cookie *http.Cookie) string {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/roles", nil)
+		req.AddCookie(cookie)
+		router.ServeHTTP(rw, req)
+		return rw.Body.String()
+	}
+
+	laptop := do("/login/alice", nil)
+	phone := do("/login/alice", nil)
+	bob := do("/login/bob", nil)
+	for _, cookie := range []*http.Cookie{laptop, phone, bob} {
+		do("/cache", cookie)
+		assert.Equal(t, `"editor"`, roles(cookie))
+	}
+
+	do("/revoke", laptop)
+	assert.Equal(t, "null", roles(laptop))
+	assert.Equal(t, "null", roles(phone))
+	assert.Equal(t, `"editor"`, roles(bob))
+
+	// Invalidating another user leaves the session of the request alone.
+	do("/cache", laptop)
+	do("/revoke/bob", laptop)
+	assert.Equal(t, `"editor"`, roles(laptop))
+	assert.Equal(t, "null", roles(bob))
+}
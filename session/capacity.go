@@ -0,0 +1,85 @@
+package session
+
+import "sync"
+
+// WithCapacityAlarm calls alarm with the number of active sessions when it reaches threshold, e.g. as
+// an early warning of memory pressure or bots creating sessions. It fires again only after the number
+// fell below threshold. Sessions are counted by their lifecycle and recounted by the store after each
+// garbage collection if it can count them. alarm runs on the goroutine of the request or collection
+// and should return quickly.
+func WithCapacityAlarm(threshold int, alarm func(current int)) Option {
+	return func(s *SessionManager) {
+		s.capacity = &capacityAlarm{threshold: threshold, alarm: alarm}
+	}
+}
+
+type capacityAlarm struct {
+	threshold int
+	alarm     func(current int)
+
+	mu     sync.Mutex
+	active int
+	fired  bool
+}
+
+// add changes the number of active sessions by delta and fires the alarm if it crossed the threshold.
+func (c *capacityAlarm) add(delta int) {
+	c.mu.Lock()
+	c.set(c.active + delta)
+}
+
+// reset sets the number of active sessions to the count of the store.
+func (c *capacityAlarm) reset(active int) {
+	c.mu.Lock()
+	c.set(active)
+}
+
+// set has to be called with mu locked, which it unlocks before calling the alarm.
+func (c *capacityAlarm) set(active int) {
+	active = max(active, 0)
+	c.active = active
+	fire := !c.fired && active >= c.threshold
+	if fire {
+		c.fired = true
+	} else if active < c.threshold {
+		c.fired = false
+	}
+	c.mu.Unlock()
+
+	if fire {
+		c.alarm(active)
+	}
+}
+
+// useCapacityAlarm counts the lifecycle hooks for WithCapacityAlarm.
+func (m *SessionManager) useCapacityAlarm() {
+	if m.capacity == nil {
+		return
+	}
+	capacity := m.capacity
+	if n := storeCount(m.store); n > 0 {
+		capacity.reset(n)
+	}
+	hooks := m.hooks
+	m.hooks.OnCreate = func(session *Session) {
+		capacity.add(1)
+		hooks.create(session)
+	}
+	m.hooks.OnDestroy = func(session *Session) {
+		capacity.add(-1)
+		hooks.destroy(session)
+	}
+	m.hooks.OnExpire = func(session *Session) {
+		capacity.add(-1)
+		hooks.expire(session)
+	}
+	observe := m.gcObserver
+	m.gcObserver = func(stats GCStats) {
+		if n := storeCount(m.store); n >= 0 {
+			capacity.reset(n)
+		}
+		if observe != nil {
+			observe(stats)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapacityAlarm(t *testing.T) {
+	store := NewInMemorySessionStore()
+	var alarms []int
+	sm := NewSessionManager(
+		WithStore(store),
+		WithAbsoluteExpiration(time.Hour),
+		WithCapacityAlarm(2, func(current int) { alarms = append(alarms, current) }),
+	)
+	t.Cleanup(func() { sm.Close() })
+	for i := range 3 {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		sess.Put("key", i)
+		sess.createdAt = time.Now().Add(-2 * time.Hour)
+		assert.NoError(t, sm.save(sess, nil))
+	}
+	assert.Equal(t, []int{2}, alarms)
+
+	// Expired sessions re-arm the alarm.
+	_, err := sm.RunGC(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, store.count())
+	for range 2 {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		sess.Put("key", "value")
+		assert.NoError(t, sm.save(sess, nil))
+	}
+	assert.Equal(t, []int{2, 2}, alarms)
+}
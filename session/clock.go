@@ -0,0 +1,55 @@
+package session
+
+import (
+	"time"
+)
+
+// Clock tells the time for the expiration of sessions, see WithClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock replaces the system clock for the creation and activity times of sessions, their
+// expiration, the collection of expired sessions by the in-memory store and the lifetime of cookies,
+// JWTs, remember-me tokens and the expiring values of sessions such as tokens, elevations, wizards
+// and rate limits. Tests can thereby advance the time instead of sleeping, and call RunGC to collect
+// synchronously.
+func WithClock(clock Clock) Option {
+	return func(s *SessionManager) {
+		s.clock = clock
+	}
+}
+
+// clockSetter is implemented by stores which expire sessions, so they use the clock of the manager.
+type clockSetter interface {
+	setClock(clock Clock)
+}
+
+// useClock passes the clock of WithClock to the configured session and remember-me stores.
+func (m *SessionManager) useClock() {
+	if m.clock == nil {
+		return
+	}
+	if store, ok := m.store.(clockSetter); ok {
+		store.setClock(m.clock)
+	}
+	if store, ok := m.rememberStore.(clockSetter); ok {
+		store.setClock(m.clock)
+	}
+}
+
+// now returns the time of the clock of WithClock, or the system time.
+func (m *SessionManager) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock.Now()
+}
+
+// now returns the time of the clock of the manager which created the session, or the system time.
+func (s *Session) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
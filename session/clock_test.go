@@ -0,0 +1,111 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestClockExpiration(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	sm := NewSessionManager(WithClock(clock))
+	t.Cleanup(func() { sm.Close() })
+	ctx := context.Background()
+
+	idle, err := sm.Create(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, clock.Now(), idle.createdAt)
+	assert.NoError(t, sm.Commit(ctx, idle))
+
+	for range 6 {
+		clock.advance(9 * time.Minute)
+		session, err := sm.Load(ctx, idle.ID())
+		assert.NoError(t, err)
+		assert.NoError(t, sm.Commit(ctx, session))
+	}
+	// Active, but created 63 minutes ago
+	clock.advance(9 * time.Minute)
+	_, err = sm.Load(ctx, idle.ID())
+	assert.ErrorIs(t, err, ErrUnknownSession)
+
+	for range 3 {
+		session, err := sm.Create(ctx)
+		assert.NoError(t, err)
+		assert.NoError(t, sm.Commit(ctx, session))
+	}
+	stats, err := sm.RunGC(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Deleted)
+
+	clock.advance(11 * time.Minute)
+	stats, err = sm.RunGC(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Deleted)
+	assert.Equal(t, 0, storeCount(sm.store))
+}
+
+func TestClockJWT(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	sm := NewSessionManager(WithClock(clock), WithJWT(true), WithSigningKey([]byte("secret")))
+	t.Cleanup(func() { sm.Close() })
+
+	sid, err := generateSessionID()
+	assert.NoError(t, err)
+	value, err := sm.encodeID(sid, clock.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	parts := strings.Split(value, ".")
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, err)
+	var claims jwtClaims
+	assert.NoError(t, json.Unmarshal(data, &claims))
+	assert.Equal(t, clock.Now().Unix(), claims.IssuedAt)
+
+	clock.advance(time.Hour - time.Second)
+	id, ok := sm.decodeID(value)
+	assert.True(t, ok)
+	assert.Equal(t, sid, id)
+	clock.advance(time.Second)
+	_, ok = sm.decodeID(value)
+	assert.False(t, ok)
+}
+
+func TestClockToken(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	sm := NewSessionManager(WithClock(clock))
+	t.Cleanup(func() { sm.Close() })
+	session, err := sm.Create(context.Background())
+	assert.NoError(t, err)
+
+	token, err := session.IssueToken("download", time.Minute)
+	assert.NoError(t, err)
+	clock.advance(time.Minute)
+	assert.True(t, session.ConsumeToken("download", token))
+
+	token, err = session.IssueToken("download", time.Minute)
+	assert.NoError(t, err)
+	clock.advance(time.Minute + time.Nanosecond)
+	assert.False(t, session.ConsumeToken("download", token))
+}
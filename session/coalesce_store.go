@@ -0,0 +1,224 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// WithWriteCoalescing delays the write of a saved session by up to window. Saves of the same session
+// within the window are merged into the pending write, the keys put or deleted by the last save
+// winning, so chatty sessions cost one store write per window. Reads see the pending session.
+// Version conflicts of the delayed write are merged with MergeChanges, other errors are logged as
+// the save already returned.
+func WithWriteCoalescing(window time.Duration) Option {
+	return func(s *SessionManager) {
+		s.coalesceWindow = window
+	}
+}
+
+// useWriteCoalescing wraps the store for WithWriteCoalescing.
+func (m *SessionManager) useWriteCoalescing() {
+	if m.coalesceWindow <= 0 {
+		return
+	}
+	m.store = &coalescingStore{
+		inner:   m.store,
+		window:  m.coalesceWindow,
+		logger:  m.logger,
+		pending: make(map[string]*pendingWrite),
+	}
+}
+
+type coalescingStore struct {
+	inner  SessionStore
+	window time.Duration
+	logger Logger
+
+	mu      sync.Mutex
+	pending map[string]*pendingWrite
+	// stopped is set by Close, later writes go to the inner store directly.
+	stopped bool
+}
+
+// coalesceConflictRetries is how often a delayed write is merged and retried after a version conflict.
+const coalesceConflictRetries = 3
+
+// pendingWrite is a session waiting for the end of its window.
+type pendingWrite struct {
+	session *Session
+	timer   *time.Timer
+	// saved is set by saves while the session is being written, so it is written again.
+	saved bool
+}
+
+func (s *coalescingStore) read(id string) *Session {
+	s.mu.Lock()
+	pending, ok := s.pending[id]
+	s.mu.Unlock()
+
+	if ok {
+		return pending.session
+	}
+	return s.inner.read(id)
+}
+
+func (s *coalescingStore) readChecked(id string) (*Session, error) {
+	s.mu.Lock()
+	pending, ok := s.pending[id]
+	s.mu.Unlock()
+
+	if ok {
+		return pending.session, nil
+	}
+	return readStore(s.inner, id)
+}
+
+func (s *coalescingStore) write(session *Session) error {
+	key := session.storeKey()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pending, ok := s.pending[key]; ok {
+		pending.saved = true
+		if pending.session != session {
+			// The sessions are copies read from the store by different requests.
+			err := MergeChanges(pending.session, session)
+			if err != nil {
+				return err
+			}
+		}
+		session.queue()
+		return nil
+	}
+	if s.stopped {
+		return s.inner.write(session)
+	}
+	s.pending[key] = &pendingWrite{
+		session: session,
+		timer:   time.AfterFunc(s.window, func() { s.flush(key) }),
+	}
+	session.queue()
+	return nil
+}
+
+// flush writes the pending session of key. The session stays pending during the write, so saves
+// in the meantime are merged into it and written with the next window.
+func (s *coalescingStore) flush(key string) {
+	s.mu.Lock()
+	pending, ok := s.pending[key]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	pending.timer.Stop()
+	pending.saved = false
+	s.mu.Unlock()
+
+	err := s.writeMerging(pending.session)
+
+	s.mu.Lock()
+	if s.pending[key] == pending {
+		if pending.saved && !s.stopped {
+			pending.timer.Reset(s.window)
+		} else {
+			delete(s.pending, key)
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		s.logger.Error("coalesced session write failed", "error", err)
+	}
+}
+
+// writeMerging writes session to the inner store. Version conflicts, e.g. of a session read from
+// the inner store before another request's write was flushed, are merged into the stored session.
+func (s *coalescingStore) writeMerging(session *Session) error {
+	err := s.inner.write(session)
+	for attempt := 0; errors.Is(err, ErrVersionConflict) && attempt < coalesceConflictRetries; attempt++ {
+		current, readErr := readStore(s.inner, session.storeKey())
+		if current == nil {
+			if readErr != nil {
+				return readErr
+			}
+			return fmt.Errorf("%w: session was destroyed", ErrVersionConflict)
+		}
+		err = MergeChanges(current, session)
+		if err != nil {
+			return err
+		}
+		err = s.inner.write(current)
+	}
+	return err
+}
+
+// flushAll writes every pending session.
+func (s *coalescingStore) flushAll() {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.pending))
+	for key := range s.pending {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+
+	for _, key := range keys {
+		s.flush(key)
+	}
+}
+
+func (s *coalescingStore) destroy(id string) error {
+	s.mu.Lock()
+	if pending, ok := s.pending[id]; ok {
+		pending.timer.Stop()
+		delete(s.pending, id)
+	}
+	s.mu.Unlock()
+
+	return s.inner.destroy(id)
+}
+
+// gc writes the pending sessions first, so their activity is known to the inner store.
+func (s *coalescingStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	s.flushAll()
+	return s.inner.gc(idleExpiration, absoluteExpiration, expired)
+}
+
+func (s *coalescingStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	if store, ok := s.inner.(gcBudgeter); ok {
+		store.setGCBudget(maxDuration, maxDeletes)
+	}
+}
+
+// invalidate drops the pending write of key, which another instance destroyed.
+func (s *coalescingStore) invalidate(key string) {
+	s.mu.Lock()
+	if pending, ok := s.pending[key]; ok {
+		pending.timer.Stop()
+		delete(s.pending, key)
+	}
+	s.mu.Unlock()
+	invalidate(s.inner, key)
+}
+
+func (s *coalescingStore) count() int {
+	return storeCount(s.inner)
+}
+
+func (s *coalescingStore) unwrap() SessionStore {
+	return s.inner
+}
+
+// Close writes the pending sessions and closes the inner store if it is an io.Closer.
+func (s *coalescingStore) Close() error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.flushAll()
+	if closer, ok := s.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
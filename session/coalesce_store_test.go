@@ -0,0 +1,105 @@
+package session
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStore counts writes.
+type countingStore struct {
+	*inMemorySessionStore
+	writes atomic.Int32
+}
+
+func (s *countingStore) write(session *Session) error {
+	s.writes.Add(1)
+	return s.inMemorySessionStore.write(session)
+}
+
+func TestWriteCoalescing(t *testing.T) {
+	inner := &countingStore{inMemorySessionStore: NewInMemorySessionStore()}
+	sm := NewSessionManager(WithStore(inner), WithWriteCoalescing(50*time.Millisecond))
+	t.Cleanup(func() { sm.Close() })
+
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("cart", 1)
+	sess.Put("theme", "dark")
+	assert.NoError(t, sm.save(sess, nil))
+
+	// Two requests holding their own copies of the session.
+	first := sess.withData(sess.values())
+	first.Put("cart", 2)
+	second := sess.withData(sess.values())
+	second.Put("page", 3)
+	second.Delete("theme")
+	assert.NoError(t, sm.save(first, nil))
+	assert.NoError(t, sm.save(second, nil))
+	assert.Zero(t, inner.writes.Load())
+	assert.Equal(t, 2, sm.read(sess.id).Get("cart"))
+
+	assert.Eventually(t, func() bool { return inner.read(sess.id) != nil }, time.Second, 10*time.Millisecond)
+	stored := inner.read(sess.id)
+	assert.Equal(t, int32(1), inner.writes.Load())
+	assert.Equal(t, map[string]any{"cart": 2, "page": 3}, stored.values())
+
+	sess.Put("cart", 4)
+	assert.NoError(t, sm.save(sess, nil))
+	assert.NoError(t, sm.Close())
+	assert.Equal(t, 4, inner.read(sess.id).Get("cart"))
+}
+
+func TestWriteCoalescingCreateOnce(t *testing.T) {
+	created := 0
+	sm := NewSessionManager(WithWriteCoalescing(time.Hour), WithHooks(Hooks{
+		OnCreate: func(*Session) { created++ },
+	}))
+	t.Cleanup(func() { sm.Close() })
+
+	sess, err := sm.Create(context.Background())
+	assert.NoError(t, err)
+	sess.Put("key", "first")
+	assert.NoError(t, sm.Commit(context.Background(), sess))
+	sess.Put("key", "second")
+	assert.NoError(t, sm.Commit(context.Background(), sess))
+	assert.Equal(t, 1, created)
+}
+
+func TestWriteCoalescingConflict(t *testing.T) {
+	inner := NewInMemorySessionStore()
+	store, err := NewEncryptedStoreFromKeys(inner, StaticKeys([]byte("0123456789abcdef")))
+	assert.NoError(t, err)
+	sm := NewSessionManager(WithStore(store), WithWriteCoalescing(time.Hour))
+	t.Cleanup(func() { sm.Close() })
+	ctx := context.Background()
+
+	sess, err := sm.Create(ctx)
+	assert.NoError(t, err)
+	sess.Put("cart", "initial")
+	assert.NoError(t, sm.Commit(ctx, sess))
+	_, err = sm.RunGC(ctx)
+	assert.NoError(t, err)
+
+	// Both requests read their copy from the encrypted store before either write was flushed.
+	first, err := sm.Load(ctx, sess.ID())
+	assert.NoError(t, err)
+	second, err := sm.Load(ctx, sess.ID())
+	assert.NoError(t, err)
+	first.Put("cart", "first")
+	assert.NoError(t, sm.Commit(ctx, first))
+	_, err = sm.RunGC(ctx)
+	assert.NoError(t, err)
+	second.Put("theme", "dark")
+	assert.NoError(t, sm.Commit(ctx, second))
+	_, err = sm.RunGC(ctx)
+	assert.NoError(t, err)
+
+	stored, err := store.readChecked(sess.ID())
+	assert.NoError(t, err)
+	assert.Equal(t, "first", stored.Get("cart"))
+	assert.Equal(t, "dark", stored.Get("theme"))
+}
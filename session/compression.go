@@ -0,0 +1,223 @@
+package session
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// compressedDataKey holds the compressed session data in the inner store.
+const compressedDataKey = "session.compressed"
+
+// Compressor compresses the encoded session data, see WithCompression. Algorithms like zstd or
+// snappy can be plugged in by implementing it with their packages, e.g. github.com/klauspost/compress.
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// Gzip compresses with compress/gzip at the default level.
+var Gzip Compressor = gzipCompressor{}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// WithCompression compresses the JSON encoded data of sessions of at least minSize bytes before
+// they are handed to the store, so large sessions take less memory and bandwidth. Smaller sessions
+// are stored as they are. Values of compressed sessions come back as decoded from JSON, e.g. numbers as float64.
+func WithCompression(compressor Compressor, minSize int) Option {
+	return func(s *SessionManager) {
+		s.compressor = compressor
+		s.compressMinSize = minSize
+	}
+}
+
+// useCompression wraps the store for WithCompression.
+func (m *SessionManager) useCompression() {
+	if m.compressor == nil {
+		return
+	}
+	m.store = &compressedStore{inner: m.store, compressor: m.compressor, minSize: m.compressMinSize, logger: m.logger}
+}
+
+type compressedStore struct {
+	inner      SessionStore
+	compressor Compressor
+	minSize    int
+	logger     Logger
+}
+
+func (s *compressedStore) read(id string) *Session {
+	session, err := s.readChecked(id)
+	if err != nil {
+		s.logger.Error("decompressing session failed", "error", err)
+		return nil
+	}
+	return session
+}
+
+func (s *compressedStore) readChecked(id string) (*Session, error) {
+	stored, err := readStore(s.inner, id)
+	if stored == nil {
+		return nil, err
+	}
+	session, err := s.decompressLazily(stored)
+	if err != nil {
+		return nil, corrupt(id, err)
+	}
+	return session, nil
+}
+
+func (s *compressedStore) write(session *Session) error {
+	if stored, ok := session.storedForm(); ok {
+		err := s.inner.write(session.withData(stored))
+		if err != nil {
+			return err
+		}
+		session.saved()
+		return nil
+	}
+
+	values := session.values()
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if len(data) >= s.minSize {
+		compressed, err := s.compressor.Compress(data)
+		if err != nil {
+			return err
+		}
+		values = map[string]any{compressedDataKey: base64.StdEncoding.EncodeToString(compressed)}
+	}
+
+	err = s.inner.write(session.withData(values))
+	if err != nil {
+		return err
+	}
+	session.saved()
+	return nil
+}
+
+func (s *compressedStore) destroy(id string) error {
+	return s.inner.destroy(id)
+}
+
+func (s *compressedStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	return s.inner.gc(idleExpiration, absoluteExpiration, func(stored *Session) {
+		session, err := s.decompress(stored)
+		if err != nil {
+			session = stored.withData(map[string]any{})
+		}
+		expired(session)
+	})
+}
+
+// sharesSessions is false, every read decodes a new copy.
+func (s *compressedStore) sharesSessions() bool {
+	return false
+}
+
+func (s *compressedStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	if store, ok := s.inner.(gcBudgeter); ok {
+		store.setGCBudget(maxDuration, maxDeletes)
+	}
+}
+
+func (s *compressedStore) invalidate(key string) {
+	invalidate(s.inner, key)
+}
+
+func (s *compressedStore) count() int {
+	return storeCount(s.inner)
+}
+
+func (s *compressedStore) unwrap() SessionStore {
+	return s.inner
+}
+
+// Close closes the inner store if it is an io.Closer.
+func (s *compressedStore) Close() error {
+	if closer, ok := s.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// decompress returns a copy of stored with its data decompressed. Uncompressed sessions are copied as they are.
+func (s *compressedStore) decompress(stored *Session) (*Session, error) {
+	values := stored.values()
+	encoded, ok := values[compressedDataKey].(string)
+	if !ok {
+		return stored.withData(values), nil
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode session %s: %w", stored.storeKey(), err)
+	}
+	values, err = s.decode(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress session %s: %w", stored.storeKey(), err)
+	}
+	return stored.withData(values), nil
+}
+
+// decompressLazily is decompress deferring the decoding of the JSON to the first use of the data.
+// The data is decompressed and validated right away, so corrupt sessions are reported on read.
+func (s *compressedStore) decompressLazily(stored *Session) (*Session, error) {
+	values := stored.values()
+	encoded, ok := values[compressedDataKey].(string)
+	if !ok {
+		return stored.withData(values), nil
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode session %s: %w", stored.storeKey(), err)
+	}
+	data, err := s.compressor.Decompress(compressed)
+	if err == nil {
+		err = checkObject(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress session %s: %w", stored.storeKey(), err)
+	}
+	return stored.withLazyData(func() map[string]any { return decodeObject(data) }), nil
+}
+
+// decode decompresses and unmarshals session data.
+func (s *compressedStore) decode(compressed []byte) (map[string]any, error) {
+	data, err := s.compressor.Decompress(compressed)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any)
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
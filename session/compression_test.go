@@ -0,0 +1,41 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompression(t *testing.T) {
+	inner := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(inner), WithCompression(Gzip, 100))
+	t.Cleanup(func() { sm.Close() })
+
+	large, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	cart := strings.Repeat("item,", 200)
+	large.Put("cart", cart)
+	large.Put("count", 200)
+	assert.NoError(t, sm.save(large, nil))
+
+	stored := inner.read(large.id)
+	compressed, ok := stored.Get(compressedDataKey).(string)
+	assert.True(t, ok)
+	assert.Less(t, len(compressed), len(cart))
+	assert.Equal(t, []string{compressedDataKey}, stored.Keys())
+
+	read := sm.read(large.id)
+	assert.Equal(t, cart, read.Get("cart"))
+	assert.Equal(t, float64(200), read.Get("count"))
+
+	small, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	small.Put("theme", "dark")
+	assert.NoError(t, sm.save(small, nil))
+	assert.Equal(t, "dark", inner.read(small.id).Get("theme"))
+	assert.Equal(t, "dark", sm.read(small.id).Get("theme"))
+
+	stored.Put(compressedDataKey, "garbage")
+	assert.Nil(t, sm.read(large.id))
+}
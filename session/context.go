@@ -0,0 +1,131 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextKey stores the requestState in the request context, where no other package can collide with it.
+type contextKey struct{}
+
+// requestState is attached once per request. The session is swapped in place by Regenerate and
+// Destroy so that every holder of the request context sees the current session.
+type requestState struct {
+	mu      sync.RWMutex
+	manager *SessionManager
+	request *http.Request
+	session *Session
+	// generation of session when it was attached, see checkLive.
+	generation uint64
+	logoutSet  bool
+	// remember is a remember-me cookie waiting to be written.
+	remember *http.Cookie
+	// corrupt is the error of a corrupt stored session replaced by a new one.
+	corrupt error
+	// cookieLost is set when the response headers were sent before the cookie, see finish.
+	cookieLost bool
+}
+
+// WithContextKey additionally stores the session under key in the gin context,
+// for code reading it with c.Get or c.MustGet.
+func WithContextKey(key string) Option {
+	return func(s *SessionManager) {
+		s.contextKey = key
+	}
+}
+
+func stateFrom(ctx context.Context) *requestState {
+	state, _ := ctx.Value(contextKey{}).(*requestState)
+	return state
+}
+
+func (s *requestState) get() (*Session, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.session != nil {
+		s.session.checkLive(s.generation)
+	}
+	return s.session, s.session != nil
+}
+
+func (s *requestState) set(session *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = session
+	if session != nil {
+		s.generation = session.generation.Load()
+	}
+}
+
+// logout marks the request as logged out, so the cookie is expired unless the new session is used.
+func (s *requestState) logout() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logoutSet = true
+}
+
+func (s *requestState) loggedOut() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.logoutSet
+}
+
+func (s *requestState) loseCookie() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cookieLost = true
+}
+
+func (s *requestState) lostCookie() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cookieLost
+}
+
+func (s *requestState) setRemember(cookie *http.Cookie) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remember = cookie
+}
+
+// takeRemember returns the pending remember-me cookie, once.
+func (s *requestState) takeRemember() *http.Cookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cookie := s.remember
+	s.remember = nil
+	return cookie
+}
+
+// FromContext returns the session attached to a request context by Handle or Middleware.
+func FromContext(ctx context.Context) (*Session, bool) {
+	return stateFrom(ctx).get()
+}
+
+// attach returns a copy of r carrying a requestState without session.
+func (m *SessionManager) attach(r *http.Request) (*http.Request, *requestState) {
+	state := &requestState{manager: m, request: r}
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, state)), state
+}
+
+// Attach attaches session to the request of c as Handle does, for handlers called without the
+// middleware, e.g. in tests. c must carry a request. The session is not saved, see Commit.
+func (m *SessionManager) Attach(c *gin.Context, session *Session) {
+	var state *requestState
+	c.Request, state = m.attach(c.Request)
+	state.set(session)
+	m.mirror(c, session)
+}
+
+// mirror stores the session under the key configured by WithContextKey.
+func (m *SessionManager) mirror(c *gin.Context, session *Session) {
+	if m.contextKey != "" {
+		c.Set(m.contextKey, session)
+	}
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		legacy bool
+	}{
+		{"default", nil, false},
+		{"legacy key", []Option{WithContextKey("session")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewSessionManager(tt.opts...)
+			router := gin.New()
+			router.Use(sm.Handle())
+			router.GET("/", func(c *gin.Context) {
+				value, ok := c.Get("session")
+				assert.Equal(t, tt.legacy, ok)
+
+				sess, err := sm.Regenerate(c)
+				assert.NoError(t, err)
+				assert.Same(t, sess, GetSession(c))
+				if tt.legacy {
+					assert.NotSame(t, sess, value)
+					assert.Same(t, sess, c.MustGet("session"))
+				}
+			})
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	}
+}
+
+func TestSessionFromWithoutRequest(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	_, ok := SessionFrom(c)
+	assert.False(t, ok)
+}
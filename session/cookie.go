@@ -0,0 +1,140 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CookiePrefix is a cookie name prefix that makes browsers enforce additional constraints on the cookie.
+type CookiePrefix string
+
+const (
+	// SecurePrefix requires the Secure attribute.
+	SecurePrefix CookiePrefix = "__Secure-"
+	// HostPrefix requires the Secure attribute, the path "/" and no domain, binding the cookie to a single host.
+	HostPrefix CookiePrefix = "__Host-"
+)
+
+// WithCookiePrefix prepends prefix to the cookie name. NewSessionManager panics if the other
+// cookie options violate the constraints of the prefix.
+func WithCookiePrefix(prefix CookiePrefix) Option {
+	return func(s *SessionManager) {
+		s.cookiePrefix = prefix
+	}
+}
+
+// WithPartitionedCookie adds the Partitioned attribute (CHIPS), so browsers blocking third party
+// cookies keep the cookie for embedded use, partitioned by the top level site. It requires the Secure attribute.
+func WithPartitionedCookie(partitioned bool) Option {
+	return func(s *SessionManager) {
+		s.partitioned = partitioned
+	}
+}
+
+// checkCookie validates the cookie options against each other and against the prefix of the
+// cookie name, whether it was set by WithCookiePrefix or as part of WithCookieName.
+func (m *SessionManager) checkCookie() error {
+	if m.partitioned && !m.secure {
+		return fmt.Errorf("partitioned cookie %q requires the Secure attribute", m.cookieName)
+	}
+
+	switch {
+	case strings.HasPrefix(m.cookieName, string(HostPrefix)):
+		if !m.secure || m.path != "/" || m.domain != "" || m.domainFunc != nil {
+			return fmt.Errorf("cookie %q requires the Secure attribute, the path / and no domain", m.cookieName)
+		}
+	case strings.HasPrefix(m.cookieName, string(SecurePrefix)):
+		if !m.secure {
+			return fmt.Errorf("cookie %q requires the Secure attribute", m.cookieName)
+		}
+	}
+	return nil
+}
+
+// maxTokenLength bounds the tokens decoded by readID. Browsers do not keep longer cookies, so longer
+// tokens are forged and rejected before they cost any decoding.
+const maxTokenLength = 4096
+
+// readID returns the session id carried by the request's cookie, if there is a valid one.
+func (m *SessionManager) readID(r *http.Request) (string, bool) {
+	token, ok := m.readToken(r)
+	if !ok || len(token) > maxTokenLength {
+		return "", false
+	}
+	return m.decodeID(token)
+}
+
+// hasCookie reports whether r carries a session cookie, valid or not.
+func (m *SessionManager) hasCookie(r *http.Request) bool {
+	_, ok := m.readToken(r)
+	return ok
+}
+
+// WithCookieExpiry embeds the absolute expiration of the session into the cookie value, so requests with
+// expired sessions are rejected without reading the store, and Max-Age never exceeds the remaining lifetime.
+// Combine it with WithSigningKey to keep clients from extending the expiration of their cookie;
+// the store still enforces the expiration in any case.
+func WithCookieExpiry(embed bool) Option {
+	return func(s *SessionManager) {
+		s.cookieExpiry = embed
+	}
+}
+
+// encodeID turns a session id expiring at expires into the cookie value. It is encrypted first,
+// then signed or wrapped in a JWT.
+func (m *SessionManager) encodeID(id string, expires time.Time) (string, error) {
+	value := id
+	if m.cookieExpiry {
+		value += "~" + strconv.FormatInt(expires.Unix(), 10)
+	}
+	if m.aeads != nil {
+		var err error
+		value, err = encrypt(m.aeads[0], value, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+	if m.jwt {
+		return signJWT(m.signingKeys[0], value, m.now(), expires)
+	}
+	if m.signingKeys != nil {
+		value = sign(m.signingKeys[0], value)
+	}
+	return value, nil
+}
+
+// decodeID is the inverse of encodeID and reports whether value is valid.
+func (m *SessionManager) decodeID(value string) (string, bool) {
+	ok := true
+	if m.jwt {
+		value, ok = verifyJWT(m.signingKeys, value, m.now())
+	} else if m.signingKeys != nil {
+		value, ok = verify(m.signingKeys, value)
+	}
+	if ok && m.aeads != nil {
+		value, ok = decrypt(m.aeads, value, nil)
+	}
+	if ok && m.cookieExpiry {
+		value, ok = unexpired(value, m.now())
+	}
+	if ok && m.idValidator != nil {
+		ok = m.idValidator(value)
+	}
+	return value, ok && value != ""
+}
+
+// unexpired splits the expiration embedded by encodeID from value and reports whether it has not passed at now.
+func unexpired(value string, now time.Time) (string, bool) {
+	i := strings.LastIndexByte(value, '~')
+	if i < 0 {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(value[i+1:], 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return "", false
+	}
+	return value[:i], true
+}
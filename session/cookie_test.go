@@ -0,0 +1,84 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookiePrefix(t *testing.T) {
+	assert.Equal(t, "__Host-session", issueCookie(t, WithCookiePrefix(HostPrefix)).Name)
+	assert.Equal(t, "__Secure-id", issueCookie(t, WithCookiePrefix(SecurePrefix), WithCookieName("id")).Name)
+
+	invalid := [][]Option{
+		{WithCookiePrefix(HostPrefix), WithCookieDomain("example.com")},
+		{WithCookiePrefix(HostPrefix), WithCookiePath("/app")},
+		{WithCookiePrefix(HostPrefix), WithCookieSecure(false)},
+		{WithCookiePrefix(SecurePrefix), WithCookieSecure(false)},
+		{WithCookieName("__Host-session"), WithCookieDomain("example.com")},
+	}
+	for _, opts := range invalid {
+		assert.Panics(t, func() { NewSessionManager(opts...) })
+	}
+}
+
+func TestPartitionedCookie(t *testing.T) {
+	assert.False(t, issueCookie(t).Partitioned)
+	assert.True(t, issueCookie(t, WithPartitionedCookie(true), WithSameSite(http.SameSiteNoneMode)).Partitioned)
+	assert.Panics(t, func() { NewSessionManager(WithPartitionedCookie(true), WithCookieSecure(false)) })
+}
+
+func TestCookieExpiry(t *testing.T) {
+	cookie := issueCookie(t, WithCookieExpiry(true), WithAbsoluteExpiration(time.Minute))
+	assert.LessOrEqual(t, cookie.MaxAge, 60)
+	assert.Greater(t, cookie.MaxAge, 50)
+	assert.Equal(t, 600, issueCookie(t).MaxAge)
+
+	sm := NewSessionManager(WithCookieExpiry(true), WithSigningKey([]byte("secret")))
+	t.Cleanup(func() { sm.Close() })
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+
+	value, err := sm.encodeID(sess.id, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	id, ok := sm.decodeID(value)
+	assert.True(t, ok)
+	assert.Equal(t, sess.id, id)
+
+	value, err = sm.encodeID(sess.id, time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	_, ok = sm.decodeID(value)
+	assert.False(t, ok)
+}
+
+func TestCookieDomainFunc(t *testing.T) {
+	sm := NewSessionManager(WithCookieDomainFunc(func(r *http.Request) string {
+		if strings.HasSuffix(r.Host, ".example.com") {
+			return ".example.com"
+		}
+		return ""
+	}))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+
+	for host, domain := range map[string]string{"tenant.example.com": "example.com", "vanity.org": ""} {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		router.ServeHTTP(rw, req)
+		assert.Equal(t, domain, rw.Result().Cookies()[0].Domain, host)
+	}
+
+	assert.Panics(t, func() {
+		NewSessionManager(WithCookiePrefix(HostPrefix), WithCookieDomainFunc(func(*http.Request) string { return "" }))
+	})
+}
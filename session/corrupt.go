@@ -0,0 +1,43 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCorruptSession is reported for stored sessions whose data cannot be decoded, e.g. after the
+// encryption key was lost. The record is destroyed and the request continues with a new session,
+// so the user is logged out instead of seeing errors. The error handler of WithErrorHandler is
+// called with it and may still abort the request.
+var ErrCorruptSession = errors.New("corrupt session data")
+
+// checkedReader is implemented by stores which decode the stored data and can tell a missing session
+// from a corrupt one.
+type checkedReader interface {
+	// readChecked is read returning an error wrapping ErrCorruptSession for undecodable data.
+	readChecked(id string) (*Session, error)
+}
+
+// readStore reads the session of key from store, reporting corrupt data if the store detects it.
+func readStore(store SessionStore, key string) (*Session, error) {
+	if r, ok := store.(checkedReader); ok {
+		return r.readChecked(key)
+	}
+	return store.read(key), nil
+}
+
+// corrupt wraps a decoding error of the session stored under key in ErrCorruptSession.
+func corrupt(key string, err error) error {
+	return fmt.Errorf("%w %s: %w", ErrCorruptSession, hashID(key), err)
+}
+
+// quarantine removes the corrupt record of key, so it does not fail every request of the session.
+func (m *SessionManager) quarantine(key string, err error) {
+	m.logger.Error("destroying corrupt session", "error", err)
+	if m.metrics != nil {
+		m.metrics.corrupted.Add(1)
+	}
+	if err := m.store.destroy(key); err != nil {
+		m.logger.Error("destroying corrupt session failed", "error", err)
+	}
+}
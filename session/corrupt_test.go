@@ -0,0 +1,62 @@
+package session
+
+import (
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorruptSession(t *testing.T) {
+	newKey := func() []byte {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		assert.NoError(t, err)
+		return key
+	}
+	inner := NewInMemorySessionStore()
+	lost, err := newAEAD(newKey())
+	assert.NoError(t, err)
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put(UserIDKey, "alice")
+	assert.NoError(t, NewEncryptedStore(inner, lost).write(sess))
+
+	// The key the session was encrypted with is gone.
+	current, err := newAEAD(newKey())
+	assert.NoError(t, err)
+	metrics := NewMetrics()
+	var handled []error
+	sm := NewSessionManager(
+		WithStore(NewEncryptedStore(inner, current)),
+		WithMetrics(metrics),
+		WithErrorHandler(func(c *gin.Context, err error) { handled = append(handled, err) }),
+	)
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		s := GetSession(c)
+		assert.Nil(t, s.Get(UserIDKey))
+		s.Put("key", "value")
+	})
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: sess.id})
+	router.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.NotEqual(t, sess.id, rw.Result().Cookies()[0].Value)
+	assert.Nil(t, inner.read(sess.id))
+	assert.Len(t, handled, 1)
+	assert.ErrorIs(t, handled[0], ErrCorruptSession)
+	var buf strings.Builder
+	_, err = metrics.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "session_corrupted_total 1\n")
+}
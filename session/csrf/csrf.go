@@ -0,0 +1,125 @@
+// Package csrf protects gin handlers against cross-site request forgery with a token stored in the session.
+//
+// Render Token in forms or pages and send it back in the X-CSRF-Token header or the csrf_token form field;
+// Middleware rejects unsafe requests without a matching token. The token is bound to the session id,
+// so it rotates when the id is regenerated, e.g. by SessionManager.Login.
+package csrf
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+)
+
+const (
+	tokenKey     = "csrf.token"
+	sessionIDKey = "csrf.session_id"
+)
+
+var (
+	// ErrNoSession is passed to the error handler when the request has no session.
+	ErrNoSession = errors.New("csrf: no session in request context")
+	// ErrInvalidToken is passed to the error handler when the token is missing or does not match.
+	ErrInvalidToken = errors.New("csrf: invalid token")
+)
+
+type config struct {
+	header       string
+	field        string
+	errorHandler func(*gin.Context, error)
+}
+
+type Option func(*config)
+
+// WithHeader sets the request header carrying the token. The default is X-CSRF-Token.
+func WithHeader(name string) Option {
+	return func(c *config) {
+		c.header = name
+	}
+}
+
+// WithField sets the form field carrying the token. The default is csrf_token.
+func WithField(name string) Option {
+	return func(c *config) {
+		c.field = name
+	}
+}
+
+// WithErrorHandler replaces the default handler that aborts with 403 Forbidden.
+// The handler has to abort the request.
+func WithErrorHandler(handler func(*gin.Context, error)) Option {
+	return func(c *config) {
+		c.errorHandler = handler
+	}
+}
+
+// Token returns the CSRF token of the request's session, creating one if needed.
+// It returns an empty string if the request has no session.
+func Token(c *gin.Context) string {
+	sess, ok := session.SessionFrom(c)
+	if !ok {
+		return ""
+	}
+	if token, ok := current(sess); ok {
+		return token
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	sess.Put(tokenKey, token)
+	sess.Put(sessionIDKey, sess.ID())
+	return token
+}
+
+// current returns the stored token unless it was issued for another session id.
+func current(sess *session.Session) (string, bool) {
+	token, _ := sess.Get(tokenKey).(string)
+	id, _ := sess.Get(sessionIDKey).(string)
+	return token, token != "" && id == sess.ID()
+}
+
+// Middleware validates the token of requests with unsafe methods. It has to run after the session middleware.
+func Middleware(opts ...Option) gin.HandlerFunc {
+	cfg := &config{
+		header: "X-CSRF-Token",
+		field:  "csrf_token",
+		errorHandler: func(c *gin.Context, err error) {
+			c.AbortWithStatus(http.StatusForbidden)
+		},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			c.Next()
+			return
+		}
+
+		sess, ok := session.SessionFrom(c)
+		if !ok {
+			cfg.errorHandler(c, ErrNoSession)
+			return
+		}
+		token, ok := current(sess)
+		sent := c.GetHeader(cfg.header)
+		if sent == "" {
+			sent = c.PostForm(cfg.field)
+		}
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(sent)) != 1 {
+			cfg.errorHandler(c, ErrInvalidToken)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+)
+
+func TestMiddleware(t *testing.T) {
+	sm := session.NewSessionManager()
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle(), Middleware())
+	router.GET("/form", func(c *gin.Context) {
+		c.String(http.StatusOK, Token(c))
+	})
+	router.POST("/submit", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/login", func(c *gin.Context) {
+		assert.NoError(t, sm.Login(c, 1))
+		c.String(http.StatusOK, Token(c))
+	})
+
+	do := func(req *http.Request, cookie *http.Cookie) *httptest.ResponseRecorder {
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	rw := do(httptest.NewRequest(http.MethodGet, "/form", nil), nil)
+	token := rw.Body.String()
+	cookie := rw.Result().Cookies()[0]
+	assert.NotEmpty(t, token)
+
+	assert.Equal(t, http.StatusForbidden, do(httptest.NewRequest(http.MethodPost, "/submit", nil), cookie).Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.Header.Set("X-CSRF-Token", "wrong")
+	assert.Equal(t, http.StatusForbidden, do(req, cookie).Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.Header.Set("X-CSRF-Token", token)
+	assert.Equal(t, http.StatusNoContent, do(req, cookie).Code)
+
+	form := url.Values{"csrf_token": {token}}
+	req = httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusNoContent, do(req, cookie).Code)
+
+	// login rotates the token
+	req = httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.Header.Set("X-CSRF-Token", token)
+	rw = do(req, cookie)
+	rotated := rw.Body.String()
+	assert.NotEqual(t, token, rotated)
+	cookie = rw.Result().Cookies()[0]
+
+	req = httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.Header.Set("X-CSRF-Token", token)
+	assert.Equal(t, http.StatusForbidden, do(req, cookie).Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.Header.Set("X-CSRF-Token", rotated)
+	assert.Equal(t, http.StatusNoContent, do(req, cookie).Code)
+}
@@ -0,0 +1,167 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// degradedSessionTTL limits the lifetime of sessions kept in memory during a store outage.
+	degradedSessionTTL = 15 * time.Minute
+	// degradedRetryInterval is how long writes skip the failed store before it is tried again.
+	degradedRetryInterval = 10 * time.Second
+)
+
+// WithDegradedMode keeps the site up while the store fails, e.g. during an outage of a remote store:
+// sessions whose write fails are kept in memory of this instance instead, for at most 15 minutes,
+// and Session.IsDegraded reports them, e.g. to disable features that need durable state. While the
+// store is failing, new writes go to memory directly; the store is retried every 10 seconds.
+func WithDegradedMode(enabled bool) Option {
+	return func(s *SessionManager) {
+		s.degradedMode = enabled
+	}
+}
+
+// IsDegraded reports whether the session is only kept in memory because the store failed, see WithDegradedMode.
+func (s *Session) IsDegraded() bool {
+	return s.degraded.Load()
+}
+
+// Degraded reports whether the store is currently failing and sessions are kept in memory, see WithDegradedMode.
+func (m *SessionManager) Degraded() bool {
+	return m.degrading != nil && m.degrading.failing()
+}
+
+// useDegradedMode wraps the store for WithDegradedMode.
+func (m *SessionManager) useDegradedMode() {
+	if !m.degradedMode {
+		return
+	}
+	fallback := NewInMemorySessionStore()
+	fallback.clock = m.clock
+	m.degrading = &degradingStore{
+		primary:  m.store,
+		fallback: fallback,
+		logger:   m.logger,
+	}
+	m.store = m.degrading
+}
+
+// degradingStore falls back to memory when writes to the primary store fail.
+type degradingStore struct {
+	primary  SessionStore
+	fallback *inMemorySessionStore
+	logger   Logger
+	// retryAt holds the Unix nanoseconds until which writes skip the primary store, zero if it is healthy.
+	retryAt atomic.Int64
+}
+
+func (s *degradingStore) failing() bool {
+	return s.retryAt.Load() != 0
+}
+
+func (s *degradingStore) read(id string) *Session {
+	if session := s.fallback.read(id); session != nil {
+		return session
+	}
+	return s.primary.read(id)
+}
+
+func (s *degradingStore) readChecked(id string) (*Session, error) {
+	if session := s.fallback.read(id); session != nil {
+		return session, nil
+	}
+	return readStore(s.primary, id)
+}
+
+func (s *degradingStore) write(session *Session) error {
+	if !session.IsDegraded() {
+		retryAt := s.retryAt.Load()
+		if retryAt == 0 || time.Now().UnixNano() >= retryAt {
+			err := writeStore(s.primary, session)
+			if err == nil || errors.Is(err, ErrVersionConflict) || errors.Is(err, ErrSessionTooLarge) {
+				if retryAt != 0 && err == nil {
+					s.retryAt.Store(0)
+					s.logger.Debug("session store recovered, leaving degraded mode")
+				}
+				return err
+			}
+			if retryAt == 0 {
+				s.logger.Error("session store failed, entering degraded mode", "error", err)
+			}
+			s.retryAt.Store(time.Now().Add(degradedRetryInterval).UnixNano())
+		}
+	}
+	session.degraded.Store(true)
+	return s.fallback.write(session)
+}
+
+func (s *degradingStore) destroy(id string) error {
+	err := s.fallback.destroy(id)
+	if err != nil {
+		return err
+	}
+	if s.failing() {
+		// The session may only be in memory, a failing primary store must not fail the logout.
+		_ = s.primary.destroy(id)
+		return nil
+	}
+	return s.primary.destroy(id)
+}
+
+// gc collects the fallback with the shorter lifetime of degraded sessions.
+func (s *degradingStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	err := s.fallback.gc(min(idleExpiration, degradedSessionTTL), min(absoluteExpiration, degradedSessionTTL), expired)
+	return errors.Join(err, s.primary.gc(idleExpiration, absoluteExpiration, expired))
+}
+
+func (s *degradingStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	s.fallback.setGCBudget(maxDuration, maxDeletes)
+	if store, ok := s.primary.(gcBudgeter); ok {
+		store.setGCBudget(maxDuration, maxDeletes)
+	}
+}
+
+func (s *degradingStore) invalidate(key string) {
+	invalidate(s.primary, key)
+}
+
+// each lists the sessions in memory, then those of the primary store.
+func (s *degradingStore) each(fn func(*Session) error) error {
+	seen := make(map[string]bool)
+	err := s.fallback.each(func(session *Session) error {
+		seen[session.storeKey()] = true
+		return fn(session)
+	})
+	if err != nil {
+		return err
+	}
+	return eachInner(s.primary, func(session *Session) error {
+		if seen[session.storeKey()] {
+			return nil
+		}
+		return fn(session)
+	})
+}
+
+func (s *degradingStore) count() int {
+	n := storeCount(s.primary)
+	if n < 0 {
+		return n
+	}
+	return n + s.fallback.count()
+}
+
+func (s *degradingStore) unwrap() SessionStore {
+	return s.primary
+}
+
+// Close closes the primary store if it is an io.Closer.
+func (s *degradingStore) Close() error {
+	if closer, ok := s.primary.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// outageStore fails writes while down is set.
+type outageStore struct {
+	*inMemorySessionStore
+	down atomic.Bool
+}
+
+func (s *outageStore) write(session *Session) error {
+	if s.down.Load() {
+		return errors.New("connection refused")
+	}
+	return s.inMemorySessionStore.write(session)
+}
+
+func TestDegradedMode(t *testing.T) {
+	primary := &outageStore{inMemorySessionStore: NewInMemorySessionStore()}
+	primary.down.Store(true)
+	sm := NewSessionManager(WithStore(primary), WithDegradedMode(true))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		count, _ := GetGenericValue[int](sess, "count")
+		sess.Put("count", count+1)
+		c.JSON(http.StatusOK, gin.H{"count": count + 1, "degraded": sess.IsDegraded()})
+	})
+	do := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	rw := do(nil)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	cookie := rw.Result().Cookies()[0]
+	assert.True(t, sm.Degraded())
+	assert.Zero(t, primary.count())
+	assert.JSONEq(t, `{"count": 2, "degraded": true}`, do(cookie).Body.String())
+
+	// The store is back once the retry interval passed.
+	primary.down.Store(false)
+	sm.degrading.retryAt.Store(1)
+	rw = do(nil)
+	assert.False(t, sm.Degraded())
+	assert.NotNil(t, primary.read(rw.Result().Cookies()[0].Value))
+	assert.JSONEq(t, `{"count": 3, "degraded": true}`, do(cookie).Body.String())
+
+	assert.NoError(t, sm.degrading.destroy(cookie.Value))
+	assert.Nil(t, sm.read(cookie.Value))
+}
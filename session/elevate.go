@@ -0,0 +1,42 @@
+package session
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// elevatedKey holds the end of the elevation as RFC 3339 time.
+const elevatedKey = "session.elevated_until"
+
+// Elevate marks the session as recently re-authenticated for d, independent of its expiration,
+// e.g. after the user entered the password again to change billing details.
+func (s *Session) Elevate(d time.Duration) {
+	s.Put(elevatedKey, s.now().Add(d).Format(time.RFC3339Nano))
+}
+
+// IsElevated reports whether the elevation of Elevate is still active.
+func (s *Session) IsElevated() bool {
+	until, _ := s.Get(elevatedKey).(string)
+	t, err := time.Parse(time.RFC3339Nano, until)
+	return err == nil && s.now().Before(t)
+}
+
+// DropElevation ends the elevation of Elevate early.
+func (s *Session) DropElevation() {
+	s.Delete(elevatedKey)
+}
+
+// RequireElevated aborts requests with 403 Forbidden unless their session is elevated.
+// It has to run after the session middleware.
+func RequireElevated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := SessionFrom(c)
+		if !ok || !session.IsElevated() {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElevate(t *testing.T) {
+	sm := NewSessionManager()
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/sudo", func(c *gin.Context) {
+		GetSession(c).Elevate(time.Minute)
+	})
+	router.GET("/expired", func(c *gin.Context) {
+		GetSession(c).Elevate(-time.Second)
+	})
+	router.GET("/billing", RequireElevated(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	do := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	assert.Equal(t, http.StatusForbidden, do("/billing", nil).Code)
+	cookie := do("/sudo", nil).Result().Cookies()[0]
+	assert.Equal(t, http.StatusNoContent, do("/billing", cookie).Code)
+	do("/expired", cookie)
+	assert.Equal(t, http.StatusForbidden, do("/billing", cookie).Code)
+
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Elevate(time.Minute)
+	assert.True(t, sess.IsElevated())
+	sess.DropElevation()
+	assert.False(t, sess.IsElevated())
+}
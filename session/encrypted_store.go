@@ -0,0 +1,277 @@
+package session
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// encryptedDataKey holds the ciphertext in the sessions handed to the inner store.
+const encryptedDataKey = "encrypted"
+
+// KeyProvider supplies the keys of an encrypted store, e.g. from the environment, a file or a KMS.
+type KeyProvider interface {
+	// Keys returns the current key first, followed by older keys that are still accepted for decryption.
+	Keys() ([][]byte, error)
+}
+
+// KeyFunc adapts a function to a KeyProvider.
+type KeyFunc func() ([][]byte, error)
+
+func (f KeyFunc) Keys() ([][]byte, error) {
+	return f()
+}
+
+// StaticKeys provides fixed keys, the current one first.
+func StaticKeys(keys ...[]byte) KeyProvider {
+	return KeyFunc(func() ([][]byte, error) {
+		return keys, nil
+	})
+}
+
+// EnvKeys reads base64 encoded keys from the environment variable name, separated by commas.
+func EnvKeys(name string) KeyProvider {
+	return KeyFunc(func() ([][]byte, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+		return decodeKeys(value)
+	})
+}
+
+// FileKeys reads base64 encoded keys from the file at path, one per line.
+func FileKeys(path string) KeyProvider {
+	return KeyFunc(func() ([][]byte, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return decodeKeys(strings.ReplaceAll(strings.TrimSpace(string(data)), "\n", ","))
+	})
+}
+
+func decodeKeys(value string) ([][]byte, error) {
+	var keys [][]byte
+	for _, encoded := range strings.Split(value, ",") {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// encryptedStore encrypts the session data before handing it to the inner store. The ciphertext is
+// bound to the store key, so it cannot be copied to another session.
+// Values come back as decoded from JSON, e.g. numbers as float64.
+type encryptedStore struct {
+	inner  SessionStore
+	aeads  []cipher.AEAD
+	logger Logger
+}
+
+// NewEncryptedStore wraps inner so it only ever sees session data encrypted with aead.
+func NewEncryptedStore(inner SessionStore, aead cipher.AEAD) *encryptedStore {
+	return &encryptedStore{
+		inner:  inner,
+		aeads:  []cipher.AEAD{aead},
+		logger: logger,
+	}
+}
+
+// NewEncryptedStoreFromKeys is NewEncryptedStore with AES-GCM keys of provider. Data is encrypted
+// with the first key and decrypted with any of them. The keys are fetched once.
+func NewEncryptedStoreFromKeys(inner SessionStore, provider KeyProvider) (*encryptedStore, error) {
+	keys, err := provider.Keys()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption key provided")
+	}
+	store := &encryptedStore{inner: inner, logger: logger}
+	for _, key := range keys {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		store.aeads = append(store.aeads, aead)
+	}
+	return store, nil
+}
+
+func (s *encryptedStore) read(id string) *Session {
+	session, err := s.readChecked(id)
+	if err != nil {
+		s.logger.Error("decrypting session failed", "error", err)
+		return nil
+	}
+	return session
+}
+
+func (s *encryptedStore) readChecked(id string) (*Session, error) {
+	stored, err := readStore(s.inner, id)
+	if stored == nil {
+		return nil, err
+	}
+	session, err := s.decryptLazily(stored)
+	if err != nil {
+		return nil, corrupt(id, err)
+	}
+	return session, nil
+}
+
+func (s *encryptedStore) write(session *Session) error {
+	if stored, ok := session.storedForm(); ok {
+		err := s.inner.write(session.withData(stored))
+		if err != nil {
+			return err
+		}
+		session.saved()
+		return nil
+	}
+
+	data, err := json.Marshal(session.values())
+	if err != nil {
+		return err
+	}
+	encrypted, err := encrypt(s.aeads[0], string(data), []byte(session.storeKey()))
+	if err != nil {
+		return err
+	}
+
+	err = s.inner.write(session.withData(map[string]any{encryptedDataKey: encrypted}))
+	if err != nil {
+		return err
+	}
+	session.saved()
+	return nil
+}
+
+func (s *encryptedStore) destroy(id string) error {
+	return s.inner.destroy(id)
+}
+
+func (s *encryptedStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	return s.inner.gc(idleExpiration, absoluteExpiration, func(stored *Session) {
+		session, err := s.decrypt(stored)
+		if err != nil {
+			session = stored.withData(map[string]any{})
+		}
+		expired(session)
+	})
+}
+
+func (s *encryptedStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	if store, ok := s.inner.(gcBudgeter); ok {
+		store.setGCBudget(maxDuration, maxDeletes)
+	}
+}
+
+// sharesSessions is false, every read decrypts a new copy.
+func (s *encryptedStore) sharesSessions() bool {
+	return false
+}
+
+func (s *encryptedStore) setClock(clock Clock) {
+	if store, ok := s.inner.(clockSetter); ok {
+		store.setClock(clock)
+	}
+}
+
+func (s *encryptedStore) setLogger(logger Logger) {
+	s.logger = logger
+	if store, ok := s.inner.(loggerSetter); ok {
+		store.setLogger(logger)
+	}
+}
+
+func (s *encryptedStore) invalidate(key string) {
+	invalidate(s.inner, key)
+}
+
+func (s *encryptedStore) count() int {
+	return storeCount(s.inner)
+}
+
+func (s *encryptedStore) unwrap() SessionStore {
+	return s.inner
+}
+
+// Close closes the inner store if it is an io.Closer.
+func (s *encryptedStore) Close() error {
+	if closer, ok := s.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (s *encryptedStore) decrypt(stored *Session) (*Session, error) {
+	encrypted, _ := stored.snapshot()[encryptedDataKey].(string)
+	data, ok := decrypt(s.aeads, encrypted, []byte(stored.storeKey()))
+	if !ok {
+		// written before the data was bound to the store key
+		data, ok = decrypt(s.aeads, encrypted, nil)
+	}
+	if !ok {
+		return nil, fmt.Errorf("failed to decrypt session %s", stored.storeKey())
+	}
+	values := make(map[string]any)
+	err := json.Unmarshal([]byte(data), &values)
+	if err != nil {
+		return nil, err
+	}
+	return stored.withData(values), nil
+}
+
+// decryptLazily decrypts stored, so tampered data is detected on read, and defers decoding the
+// data to its first use. Sessions encrypted with an old key or without the store key are decoded
+// right away, so they are encrypted with the current key when written.
+func (s *encryptedStore) decryptLazily(stored *Session) (*Session, error) {
+	encrypted, _ := stored.snapshot()[encryptedDataKey].(string)
+	data, ok := decrypt(s.aeads[:1], encrypted, []byte(stored.storeKey()))
+	if !ok {
+		return s.decrypt(stored)
+	}
+	if err := checkObject([]byte(data)); err != nil {
+		return nil, fmt.Errorf("failed to decode session %s: %w", stored.storeKey(), err)
+	}
+	return stored.withLazyData(func() map[string]any { return decodeObject([]byte(data)) }), nil
+}
+
+// withData returns a copy of the session carrying data instead of its own.
+func (s *Session) withData(data map[string]any) *Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	session := &Session{
+		createdAt:      s.createdAt,
+		id:             s.id,
+		keys:           keys,
+		fresh:          s.fresh,
+		version:        s.version,
+		schema:         s.schema,
+		cookieIssuedAt: s.cookieIssuedAt,
+		key:            s.key,
+		ip:             s.ip,
+		userAgent:      s.userAgent,
+		agent:          s.agent,
+		clock:          s.clock,
+	}
+	session.setData(data)
+	session.lastActivityAt.Store(s.lastActivityAt.Load())
+	session.degraded.Store(s.degraded.Load())
+	return session
+}
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	oldKey, newKey := []byte("0123456789abcdef"), []byte("fedcba9876543210")
+	inner := NewInMemorySessionStore()
+	store, err := NewEncryptedStoreFromKeys(inner, StaticKeys(oldKey))
+	assert.NoError(t, err)
+
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		count, _ := GetGenericValue[float64](sess, "count")
+		sess.Put("count", count+1)
+		sess.Put("secret", "swordfish")
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookie := rw.Result().Cookies()[0]
+
+	stored := inner.read(cookie.Value)
+	assert.Nil(t, stored.Get("secret"))
+	encrypted, _ := stored.Get(encryptedDataKey).(string)
+	assert.NotEmpty(t, encrypted)
+	assert.False(t, strings.Contains(encrypted, "swordfish"))
+
+	// rotate the key, data encrypted with the old key is still readable
+	t.Setenv("SESSION_KEYS", base64.StdEncoding.EncodeToString(newKey)+","+base64.StdEncoding.EncodeToString(oldKey))
+	rotated, err := NewEncryptedStoreFromKeys(inner, EnvKeys("SESSION_KEYS"))
+	assert.NoError(t, err)
+	sm.store = rotated
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	sess := rotated.read(cookie.Value)
+	assert.Equal(t, 2.0, sess.Get("count"))
+	assert.Equal(t, "swordfish", sess.Get("secret"))
+	assert.Nil(t, store.read(cookie.Value))
+
+	_, err = NewEncryptedStoreFromKeys(inner, EnvKeys("MISSING_SESSION_KEYS"))
+	assert.Error(t, err)
+}
+
+func TestEncryptedStoreBindsStoreKey(t *testing.T) {
+	block, err := aes.NewCipher([]byte("0123456789abcdef"))
+	assert.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	assert.NoError(t, err)
+	inner := NewInMemorySessionStore()
+	store := NewEncryptedStore(inner, aead)
+
+	victim, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	victim.Put("user", "victim")
+	assert.NoError(t, store.write(victim))
+	attacker, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	attacker.Put("user", "attacker")
+	assert.NoError(t, store.write(attacker))
+
+	// the ciphertext of one session does not decrypt as another one
+	copied := inner.read(attacker.id).Get(encryptedDataKey)
+	assert.NoError(t, inner.write(inner.read(victim.id).withData(map[string]any{encryptedDataKey: copied})))
+	_, err = store.readChecked(victim.id)
+	assert.ErrorIs(t, err, ErrCorruptSession)
+
+	// records written before the data was bound to the store key are still readable
+	legacy, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	encrypted, err := encrypt(aead, `{"user":"legacy"}`, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, inner.write(legacy.withData(map[string]any{encryptedDataKey: encrypted})))
+	assert.Equal(t, "legacy", store.read(legacy.id).Get("user"))
+}
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// WithEncryptionKey encrypts the cookie value with AES-GCM, so the session id never appears client side.
+// The key must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+// Every cookie gets a fresh nonce, so the value held by the browser changes whenever the cookie is sent.
+func WithEncryptionKey(key []byte) Option {
+	return WithEncryptionKeys(key)
+}
+
+// WithEncryptionKeys is WithEncryptionKey with key rotation: cookies are encrypted with current,
+// while cookies encrypted with the previous keys can still be decrypted.
+func WithEncryptionKeys(current []byte, previous ...[]byte) Option {
+	return func(s *SessionManager) {
+		s.aeads = nil
+		for _, key := range append([][]byte{current}, previous...) {
+			aead, err := newAEAD(key)
+			if err != nil {
+				panic(err)
+			}
+			s.aeads = append(s.aeads, aead)
+		}
+	}
+}
+
+// newAEAD returns AES-GCM for a 16, 24 or 32 byte key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt seals value with a fresh nonce. additionalData is authenticated but not encrypted, decrypt
+// must be given the same.
+func encrypt(aead cipher.AEAD, value string, additionalData []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), additionalData)), nil
+}
+
+// decrypt returns the value encrypted by encrypt and whether one of aeads could decrypt it.
+func decrypt(aeads []cipher.AEAD, encrypted string, additionalData []byte) (string, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", false
+	}
+	for _, aead := range aeads {
+		if len(data) < aead.NonceSize() {
+			continue
+		}
+		value, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+		if err == nil {
+			return string(value), true
+		}
+	}
+	return "", false
+}
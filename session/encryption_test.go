@@ -0,0 +1,61 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptionKey(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"encrypted", []Option{WithEncryptionKey([]byte("0123456789abcdef"))}},
+		{"encrypted and signed", []Option{WithEncryptionKey([]byte("0123456789abcdef")), WithSigningKey([]byte("secret"))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewSessionManager(tt.opts...)
+			router := gin.New()
+			router.Use(sm.Handle())
+			var id string
+			router.GET("/", func(c *gin.Context) {
+				sess := GetSession(c)
+				id = sess.ID()
+				count, _ := GetGenericValue[int](sess, "count")
+				sess.Put("count", count+1)
+				c.String(http.StatusOK, "%d", count+1)
+			})
+			do := func(value string) (string, *http.Cookie) {
+				rw := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				if value != "" {
+					req.AddCookie(&http.Cookie{Name: "session", Value: value})
+				}
+				router.ServeHTTP(rw, req)
+				return rw.Body.String(), rw.Result().Cookies()[0]
+			}
+
+			_, cookie := do("")
+			assert.NotContains(t, cookie.Value, id)
+
+			body, next := do(cookie.Value)
+			assert.Equal(t, "2", body)
+			assert.NotEqual(t, cookie.Value, next.Value)
+
+			for _, forged := range []string{id, strings.ToUpper(cookie.Value), "AAAA"} {
+				body, _ = do(forged)
+				assert.Equal(t, "1", body, forged)
+			}
+		})
+	}
+}
+
+func TestInvalidEncryptionKey(t *testing.T) {
+	assert.Panics(t, func() { NewSessionManager(WithEncryptionKey([]byte("short"))) })
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType identifies a session lifecycle event.
+type EventType string
+
+const (
+	EventCreated     EventType = "created"
+	EventSaved       EventType = "saved"
+	EventExpired     EventType = "expired"
+	EventDestroyed   EventType = "destroyed"
+	EventRegenerated EventType = "regenerated"
+)
+
+// Event is a session lifecycle event of SessionManager.Events.
+type Event struct {
+	Type      EventType
+	Time      time.Time
+	SessionID string
+	// PreviousID is the id the session had before an EventRegenerated.
+	PreviousID string
+	// Data is the session data at the time of the event, it must not be modified.
+	Data map[string]any
+}
+
+// WithEvents makes SessionManager.Events deliver lifecycle events through a channel buffering up to
+// size of them. Events are never waited for: when the buffer is full they are dropped and counted
+// by SessionManager.DroppedEvents.
+func WithEvents(size int) Option {
+	return func(s *SessionManager) {
+		s.events = &eventStream{ch: make(chan Event, size)}
+	}
+}
+
+type eventStream struct {
+	mu      sync.RWMutex
+	ch      chan Event
+	closed  bool
+	dropped atomic.Uint64
+}
+
+func (e *eventStream) emit(event Event) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.ch <- event:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+func (e *eventStream) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.closed {
+		e.closed = true
+		close(e.ch)
+	}
+}
+
+// Events returns the channel of lifecycle events, it is nil without WithEvents and closed by Close.
+func (m *SessionManager) Events() <-chan Event {
+	if m.events == nil {
+		return nil
+	}
+	return m.events.ch
+}
+
+// DroppedEvents returns the number of events dropped because the channel of Events was full.
+func (m *SessionManager) DroppedEvents() uint64 {
+	if m.events == nil {
+		return 0
+	}
+	return m.events.dropped.Load()
+}
+
+// useEvents emits the lifecycle hooks as events for WithEvents.
+func (m *SessionManager) useEvents() {
+	if m.events == nil {
+		return
+	}
+	hooks := m.hooks
+	m.hooks.OnCreate = func(session *Session) {
+		m.emit(EventCreated, session, "")
+		hooks.create(session)
+	}
+	m.hooks.OnDestroy = func(session *Session) {
+		m.emit(EventDestroyed, session, "")
+		hooks.destroy(session)
+	}
+	m.hooks.OnExpire = func(session *Session) {
+		m.emit(EventExpired, session, "")
+		hooks.expire(session)
+	}
+	m.hooks.OnRegenerate = func(oldID string, session *Session) {
+		m.emit(EventRegenerated, session, oldID)
+		hooks.regenerate(oldID, session)
+	}
+}
+
+func (m *SessionManager) emit(typ EventType, session *Session, previousID string) {
+	if m.events == nil {
+		return
+	}
+	m.events.emit(Event{
+		Type:       typ,
+		Time:       m.now(),
+		SessionID:  session.id,
+		PreviousID: previousID,
+		Data:       session.snapshot(),
+	})
+}
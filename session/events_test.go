@@ -0,0 +1,76 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	sm := NewSessionManager(WithEvents(16))
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+	router.GET("/regenerate", func(c *gin.Context) {
+		_, err := sm.Regenerate(c)
+		assert.NoError(t, err)
+	})
+	router.GET("/destroy", func(c *gin.Context) {
+		assert.NoError(t, sm.Destroy(c))
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookie := rw.Result().Cookies()[0]
+	req := httptest.NewRequest(http.MethodGet, "/regenerate", nil)
+	req.AddCookie(cookie)
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	regenerated := rw.Result().Cookies()[0]
+	req = httptest.NewRequest(http.MethodGet, "/destroy", nil)
+	req.AddCookie(regenerated)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.NoError(t, sm.Close())
+
+	var events []Event
+	for event := range sm.Events() {
+		events = append(events, event)
+	}
+	var types []EventType
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []EventType{EventCreated, EventSaved, EventRegenerated, EventSaved, EventDestroyed}, types)
+	assert.Equal(t, cookie.Value, events[0].SessionID)
+	assert.Equal(t, "value", events[1].Data["key"])
+	assert.Equal(t, cookie.Value, events[2].PreviousID)
+	assert.Equal(t, regenerated.Value, events[2].SessionID)
+	assert.Zero(t, sm.DroppedEvents())
+}
+
+func TestEventsDropped(t *testing.T) {
+	sm := NewSessionManager(WithEvents(1))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Len(t, sm.Events(), 1)
+	assert.Equal(t, uint64(1), sm.DroppedEvents())
+}
+
+func TestEventsDisabled(t *testing.T) {
+	sm := NewSessionManager()
+	t.Cleanup(func() { sm.Close() })
+
+	assert.Nil(t, sm.Events())
+}
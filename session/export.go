@@ -0,0 +1,196 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+)
+
+// sessionIterator is implemented by stores which can list their sessions, see Export.
+type sessionIterator interface {
+	// each calls fn for every stored session until fn returns an error.
+	each(fn func(*Session) error) error
+}
+
+// ErrNotIterable is returned by Export for stores which cannot list their sessions.
+var ErrNotIterable = errors.New("store cannot list its sessions")
+
+// Export writes every stored session to w as JSON lines, one object per session:
+//
+//	{"Id":"…","Data":{"count":1},"CreatedAt":"2024-01-02T15:04:05Z","LastActivityAt":"…","Version":3,
+//	 "CookieIssuedAt":"…","IP":"203.0.113.0","UserAgent":"…","Agent":"…"}
+//
+// Id is the store key, the hash of the session id with WithHashedIDs. Times are RFC 3339. Data is
+// encoded as JSON, so Import restores numbers as float64 like the file store does. The export contains
+// the session ids and data and has to be protected like the store itself.
+func (m *SessionManager) Export(ctx context.Context, w io.Writer) error {
+	iterator, ok := m.store.(sessionIterator)
+	if !ok {
+		return ErrNotIterable
+	}
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	err := iterator.each(func(session *Session) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return encoder.Encode(exported(session))
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Import writes the sessions of an Export read from r to the store and returns how many it wrote.
+// Sessions which expired meanwhile or which the store already holds are skipped, sessions of
+// logged in users are added to the user index.
+func (m *SessionManager) Import(ctx context.Context, r io.Reader) (int, error) {
+	decoder := json.NewDecoder(r)
+	imported := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return imported, err
+		}
+		var stored expSession
+		err := decoder.Decode(&stored)
+		if errors.Is(err, io.EOF) {
+			return imported, nil
+		}
+		if err != nil {
+			return imported, err
+		}
+		session := stored.session()
+		session.clock = m.clock
+		now := m.now()
+		if now.Sub(session.createdAt) > m.absoluteExpiration ||
+			now.Sub(session.getLastActivity()) > m.idleExpiration ||
+			m.store.read(session.storeKey()) != nil {
+			continue
+		}
+		err = m.store.write(session)
+		if err != nil {
+			return imported, err
+		}
+		if userID, ok := indexedUser(session); ok {
+			err = m.userIndex.AddUserSession(userKey(userID), session.storeKey())
+			if err != nil {
+				return imported, err
+			}
+		}
+		imported++
+	}
+}
+
+func (s *inMemorySessionStore) each(fn func(*Session) error) error {
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		sessions := make([]*Session, 0, len(shard.sessions))
+		for _, elem := range shard.sessions {
+			sessions = append(sessions, elem.Value.(*storeEntry).session)
+		}
+		shard.mu.RUnlock()
+
+		for _, session := range sessions {
+			if err := fn(session); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fileStore) each(fn func(*Session) error) error {
+	f.mu.RLock()
+	data, err := os.ReadFile(f.fileName)
+	f.mu.RUnlock()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	stored := make(map[string]expSession)
+	if len(data) != 0 {
+		err = json.Unmarshal(data, &stored)
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range stored {
+		if err := fn(s.session()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachInner iterates the sessions of the store wrapped by another one.
+func eachInner(inner SessionStore, fn func(*Session) error) error {
+	iterator, ok := inner.(sessionIterator)
+	if !ok {
+		return ErrNotIterable
+	}
+	return iterator.each(fn)
+}
+
+func (s *encryptedStore) each(fn func(*Session) error) error {
+	return eachInner(s.inner, func(stored *Session) error {
+		session, err := s.decrypt(stored)
+		if err != nil {
+			return err
+		}
+		return fn(session)
+	})
+}
+
+func (s *compressedStore) each(fn func(*Session) error) error {
+	return eachInner(s.inner, func(stored *Session) error {
+		session, err := s.decompress(stored)
+		if err != nil {
+			return err
+		}
+		return fn(session)
+	})
+}
+
+// each writes the queue first, so the sessions are complete.
+func (s *asyncStore) each(fn func(*Session) error) error {
+	s.flush()
+	return eachInner(s.inner, fn)
+}
+
+// each writes the pending sessions first, so the sessions are complete.
+func (s *coalescingStore) each(fn func(*Session) error) error {
+	s.flushAll()
+	return eachInner(s.inner, fn)
+}
+
+func (s *metricsStore) each(fn func(*Session) error) error {
+	return eachInner(s.inner, fn)
+}
+
+func (s *invalidatingStore) each(fn func(*Session) error) error {
+	return eachInner(s.inner, fn)
+}
+
+// each lists the sessions of new, then those of old new does not have.
+func (s *migratingStore) each(fn func(*Session) error) error {
+	seen := make(map[string]bool)
+	err := eachInner(s.new, func(session *Session) error {
+		seen[session.storeKey()] = true
+		return fn(session)
+	})
+	if err != nil {
+		return err
+	}
+	return eachInner(s.old, func(session *Session) error {
+		if seen[session.storeKey()] {
+			return nil
+		}
+		return fn(session)
+	})
+}
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+	source := NewSessionManager(WithEncryptionKey(key))
+	t.Cleanup(func() { source.Close() })
+	alice, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	alice.Put(UserIDKey, "alice")
+	alice.Put("theme", "dark")
+	assert.NoError(t, source.save(alice, nil))
+	anonymous, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	anonymous.Put("count", 1)
+	assert.NoError(t, source.save(anonymous, nil))
+
+	var buf bytes.Buffer
+	assert.NoError(t, source.Export(context.Background(), &buf))
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+	assert.NotContains(t, buf.String(), "session.encrypted")
+
+	store := NewInMemorySessionStore()
+	target := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { target.Close() })
+	imported, err := target.Import(context.Background(), bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, imported)
+	assert.Equal(t, "dark", store.read(alice.id).Get("theme"))
+	assert.Equal(t, float64(1), store.read(anonymous.id).Get("count"))
+	assert.Equal(t, alice.createdAt.UnixNano(), store.read(alice.id).createdAt.UnixNano())
+	keys, err := target.userIndex.UserSessions("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{alice.id}, keys)
+
+	// Sessions the store already holds are skipped.
+	imported, err = target.Import(context.Background(), bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Zero(t, imported)
+}
+
+func TestImportSkipsExpired(t *testing.T) {
+	sm := NewSessionManager(WithAbsoluteExpiration(time.Hour))
+	t.Cleanup(func() { sm.Close() })
+	line := `{"Id":"expired","Data":{"key":"value"},"CreatedAt":"2000-01-01T00:00:00Z","LastActivityAt":"2000-01-01T00:00:00Z","Version":1}`
+
+	imported, err := sm.Import(context.Background(), strings.NewReader(line+"\n"))
+	assert.NoError(t, err)
+	assert.Zero(t, imported)
+	assert.Nil(t, sm.read("expired"))
+}
+
+func TestExportNotIterable(t *testing.T) {
+	sm := NewSessionManager(WithStore(struct{ SessionStore }{NewInMemorySessionStore()}))
+	t.Cleanup(func() { sm.Close() })
+
+	assert.ErrorIs(t, sm.Export(context.Background(), &bytes.Buffer{}), ErrNotIterable)
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// WithExpvar publishes the active sessions, store errors and garbage collection statistics with expvar,
+// served as JSON on /debug/vars by the default mux. The variable is named "session" unless
+// WithExpvarNamespace is given. A later manager with the same namespace replaces the published one.
+func WithExpvar(enabled bool) Option {
+	return func(s *SessionManager) {
+		s.expvar = enabled
+	}
+}
+
+// WithExpvarNamespace sets the name of the expvar variable of WithExpvar.
+func WithExpvarNamespace(namespace string) Option {
+	return func(s *SessionManager) {
+		s.expvarNamespace = namespace
+	}
+}
+
+// gcTotals accumulates the statistics of the garbage collections for expvar.
+type gcTotals struct {
+	runs    atomic.Uint64
+	deleted atomic.Uint64
+	errors  atomic.Uint64
+	last    atomic.Pointer[GCStats]
+}
+
+func (t *gcTotals) observe(stats GCStats) {
+	t.runs.Add(1)
+	t.deleted.Add(uint64(stats.Deleted))
+	if stats.Err != nil {
+		t.errors.Add(1)
+	}
+	t.last.Store(&stats)
+}
+
+var (
+	expvarMu       sync.Mutex
+	expvarManagers = make(map[string]*SessionManager)
+)
+
+// useExpvar records metrics and publishes them for WithExpvar. It has to run before useMetrics.
+func (m *SessionManager) useExpvar() {
+	if !m.expvar {
+		return
+	}
+	if m.expvarNamespace == "" {
+		m.expvarNamespace = "session"
+	}
+	if m.metrics == nil {
+		m.metrics = NewMetrics()
+	}
+	m.gcTotals = &gcTotals{}
+	observe := m.gcObserver
+	m.gcObserver = func(stats GCStats) {
+		m.gcTotals.observe(stats)
+		if observe != nil {
+			observe(stats)
+		}
+	}
+
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if _, ok := expvarManagers[m.expvarNamespace]; !ok {
+		if expvar.Get(m.expvarNamespace) != nil {
+			panic(fmt.Sprintf("session: expvar %q is already published", m.expvarNamespace))
+		}
+		namespace := m.expvarNamespace
+		expvar.Publish(namespace, expvar.Func(func() any {
+			expvarMu.Lock()
+			manager := expvarManagers[namespace]
+			expvarMu.Unlock()
+			return manager.expvarValue()
+		}))
+	}
+	expvarManagers[m.expvarNamespace] = m
+}
+
+// expvarValue returns the published value of WithExpvar.
+func (m *SessionManager) expvarValue() map[string]any {
+	metrics := m.metrics
+	created, destroyed, expired := metrics.created.Load(), metrics.destroyed.Load(), metrics.expired.Load()
+	active := int64(storeCount(m.store))
+	if active < 0 {
+		active = int64(created) - int64(destroyed) - int64(expired)
+	}
+	storeErrors := make(map[string]uint64, len(storeOps))
+	for _, op := range storeOps {
+		storeErrors[op] = metrics.storeErrors[op].Load()
+	}
+	gc := map[string]any{
+		"runs":    m.gcTotals.runs.Load(),
+		"deleted": m.gcTotals.deleted.Load(),
+		"errors":  m.gcTotals.errors.Load(),
+	}
+	if last := m.gcTotals.last.Load(); last != nil {
+		gc["last_scanned"] = last.Scanned
+		gc["last_deleted"] = last.Deleted
+		gc["last_duration_seconds"] = last.Took.Seconds()
+	}
+	return map[string]any{
+		"active":       active,
+		"created":      created,
+		"destroyed":    destroyed,
+		"expired":      expired,
+		"corrupted":    metrics.corrupted.Load(),
+		"store_errors": storeErrors,
+		"gc":           gc,
+	}
+}
@@ -0,0 +1,58 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpvar(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store), WithExpvar(true), WithExpvarNamespace("session_test"), WithIdleExpiration(time.Minute))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	expired, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	expired.setLastActivity(time.Now().Add(-time.Hour))
+	assert.NoError(t, store.write(expired))
+	_, err = sm.RunGC(context.Background())
+	assert.NoError(t, err)
+
+	var value struct {
+		Active  int64 `json:"active"`
+		Created uint64
+		Expired uint64
+		GC      struct {
+			Runs        uint64
+			Deleted     uint64
+			LastScanned int `json:"last_scanned"`
+		}
+		StoreErrors map[string]uint64 `json:"store_errors"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(expvar.Get("session_test").String()), &value))
+	assert.Equal(t, int64(1), value.Active)
+	assert.Equal(t, uint64(1), value.Created)
+	assert.Equal(t, uint64(1), value.Expired)
+	assert.Equal(t, uint64(1), value.GC.Runs)
+	assert.Equal(t, uint64(1), value.GC.Deleted)
+	assert.Equal(t, 2, value.GC.LastScanned)
+	assert.Zero(t, value.StoreErrors["write"])
+
+	// a second manager takes the namespace over instead of panicking
+	other := NewSessionManager(WithExpvar(true), WithExpvarNamespace("session_test"))
+	t.Cleanup(func() { other.Close() })
+	assert.NoError(t, json.Unmarshal([]byte(expvar.Get("session_test").String()), &value))
+	assert.Equal(t, int64(0), value.Active)
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ErrInjectedFault is the default error of failures injected by NewFaultyStore.
+var ErrInjectedFault = errors.New("injected store fault")
+
+// Fault describes the failures injected into one kind of store operation.
+type Fault struct {
+	// Latency delays every call, plus a random part of up to Jitter.
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is the probability between 0 and 1 that a call fails without reaching the inner store.
+	// Failed reads return no session, the others Err.
+	ErrorRate float64
+	// PartialRate is the probability that a call which did not fail reaches the inner store but still
+	// returns Err, like a timeout after the store applied a write.
+	PartialRate float64
+	// Err is returned by failed calls, ErrInjectedFault if nil.
+	Err error
+}
+
+// Faults configures NewFaultyStore per operation.
+type Faults struct {
+	Read    Fault
+	Write   Fault
+	Destroy Fault
+	GC      Fault
+}
+
+// NewFaultyStore wraps inner with injected latency and failures, to test error handlers, WithStoreRetry
+// and WithDegradedMode against an unreliable store in CI. The faults can be changed with SetFaults.
+func NewFaultyStore(inner SessionStore, faults Faults) *faultyStore {
+	return &faultyStore{inner: inner, faults: faults}
+}
+
+type faultyStore struct {
+	inner SessionStore
+
+	mu     sync.RWMutex
+	faults Faults
+}
+
+// SetFaults replaces the injected faults, e.g. to start or end an outage.
+func (s *faultyStore) SetFaults(faults Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = faults
+}
+
+// inject waits for the latency of the fault of op and reports whether the call fails before and after
+// reaching the inner store.
+func (s *faultyStore) inject(op func(Faults) Fault) (fault Fault, fail, partial bool) {
+	s.mu.RLock()
+	fault = op(s.faults)
+	s.mu.RUnlock()
+
+	delay := fault.Latency
+	if fault.Jitter > 0 {
+		delay += rand.N(fault.Jitter)
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if fault.Err == nil {
+		fault.Err = ErrInjectedFault
+	}
+	fail = rand.Float64() < fault.ErrorRate
+	partial = !fail && rand.Float64() < fault.PartialRate
+	return fault, fail, partial
+}
+
+func (s *faultyStore) read(id string) *Session {
+	session, _ := s.readChecked(id)
+	return session
+}
+
+func (s *faultyStore) readChecked(id string) (*Session, error) {
+	_, fail, partial := s.inject(func(f Faults) Fault { return f.Read })
+	if fail {
+		return nil, nil
+	}
+	session, err := readStore(s.inner, id)
+	if partial {
+		return nil, err
+	}
+	return session, err
+}
+
+func (s *faultyStore) write(session *Session) error {
+	fault, fail, partial := s.inject(func(f Faults) Fault { return f.Write })
+	if fail {
+		return fault.Err
+	}
+	err := writeStore(s.inner, session)
+	if err == nil && partial {
+		return fault.Err
+	}
+	return err
+}
+
+func (s *faultyStore) destroy(id string) error {
+	fault, fail, partial := s.inject(func(f Faults) Fault { return f.Destroy })
+	if fail {
+		return fault.Err
+	}
+	err := s.inner.destroy(id)
+	if err == nil && partial {
+		return fault.Err
+	}
+	return err
+}
+
+func (s *faultyStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	fault, fail, partial := s.inject(func(f Faults) Fault { return f.GC })
+	if fail {
+		return fault.Err
+	}
+	err := s.inner.gc(idleExpiration, absoluteExpiration, expired)
+	if err == nil && partial {
+		return fault.Err
+	}
+	return err
+}
+
+func (s *faultyStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	if store, ok := s.inner.(gcBudgeter); ok {
+		store.setGCBudget(maxDuration, maxDeletes)
+	}
+}
+
+func (s *faultyStore) setClock(clock Clock) {
+	if store, ok := s.inner.(clockSetter); ok {
+		store.setClock(clock)
+	}
+}
+
+func (s *faultyStore) sharesSessions() bool {
+	return sharesSessions(s.inner)
+}
+
+func (s *faultyStore) invalidate(key string) {
+	invalidate(s.inner, key)
+}
+
+func (s *faultyStore) each(fn func(*Session) error) error {
+	return eachInner(s.inner, fn)
+}
+
+func (s *faultyStore) count() int {
+	return storeCount(s.inner)
+}
+
+func (s *faultyStore) unwrap() SessionStore {
+	return s.inner
+}
+
+// Close closes the inner store if it is an io.Closer.
+func (s *faultyStore) Close() error {
+	if closer, ok := s.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultyStore(t *testing.T) {
+	inner := NewInMemorySessionStore()
+	timeout := errors.New("i/o timeout")
+	store := NewFaultyStore(inner, Faults{
+		Read:  Fault{ErrorRate: 1},
+		Write: Fault{PartialRate: 1, Err: timeout},
+	})
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+
+	// The write reaches the store, but its outcome is lost.
+	assert.ErrorIs(t, store.write(sess), timeout)
+	assert.NotNil(t, inner.read(sess.id))
+	assert.Nil(t, store.read(sess.id))
+
+	store.SetFaults(Faults{Read: Fault{Latency: 20 * time.Millisecond}, Destroy: Fault{ErrorRate: 1}})
+	start := time.Now()
+	assert.NotNil(t, store.read(sess.id))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.ErrorIs(t, store.destroy(sess.id), ErrInjectedFault)
+	assert.NotNil(t, inner.read(sess.id))
+
+	store.SetFaults(Faults{})
+	assert.NoError(t, store.destroy(sess.id))
+	assert.Nil(t, inner.read(sess.id))
+}
+
+func TestFaultyStoreDegradedMode(t *testing.T) {
+	store := NewFaultyStore(NewInMemorySessionStore(), Faults{Write: Fault{ErrorRate: 1}})
+	sm := NewSessionManager(WithStore(store), WithStoreRetry(2, time.Millisecond, nil), WithDegradedMode(true))
+	t.Cleanup(func() { sm.Close() })
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+
+	assert.NoError(t, sm.save(sess, nil))
+	assert.True(t, sm.Degraded())
+	assert.True(t, sm.read(sess.id).IsDegraded())
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// fuzzManagers covers the decoding paths of cookie values.
+func fuzzManagers(t testing.TB) []*SessionManager {
+	key := []byte("0123456789abcdef")
+	managers := []*SessionManager{
+		NewSessionManager(),
+		NewSessionManager(WithSigningKey(key)),
+		NewSessionManager(WithEncryptionKey(key), WithCookieExpiry(true)),
+		NewSessionManager(WithSigningKey(key), WithEncryptionKey(key), WithCookieExpiry(true)),
+		NewSessionManager(WithSigningKey(key), WithJWT(true)),
+	}
+	t.Cleanup(func() {
+		for _, sm := range managers {
+			sm.Close()
+		}
+	})
+	return managers
+}
+
+func FuzzDecodeID(f *testing.F) {
+	signedHeader := jwtHeader + "." + jwtSignature([]byte("0123456789abcdef"), jwtHeader)
+	for _, seed := range []string{"", ".", "~", "a~1", "a.b", "a.b.c", jwtHeader + ".", signedHeader, "AAAA", strings.Repeat("A", 64)} {
+		f.Add(seed)
+	}
+	managers := fuzzManagers(f)
+	f.Fuzz(func(t *testing.T, value string) {
+		for _, sm := range managers {
+			sm.decodeID(value)
+
+			if value == "" || !utf8.ValidString(value) || (sm.idValidator != nil && !sm.idValidator(value)) {
+				continue
+			}
+			token, err := sm.encodeID(value, time.Now().Add(time.Hour))
+			assert.NoError(t, err)
+			id, ok := sm.decodeID(token)
+			assert.True(t, ok)
+			assert.Equal(t, value, id)
+		}
+	})
+}
+
+func FuzzHandle(f *testing.F) {
+	for _, seed := range []string{"", "session_id=", "session_id=abc", "session_id=\"abc\"", "session_id=a.b; session_id=c", "=;;="} {
+		f.Add(seed, "")
+	}
+	f.Add("", "Bearer "+jwtHeader+".")
+	managers := fuzzManagers(f)
+	routers := make([]*gin.Engine, len(managers))
+	for i, sm := range managers {
+		routers[i] = gin.New()
+		routers[i].Use(sm.Handle())
+		routers[i].GET("/", func(c *gin.Context) { GetSession(c).Put("visited", true) })
+	}
+	f.Fuzz(func(t *testing.T, cookie, authorization string) {
+		for _, router := range routers {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Cookie", cookie)
+			req.Header.Set("Authorization", authorization)
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+			assert.Equal(t, http.StatusOK, rw.Code)
+		}
+	})
+}
+
+func TestReadIDRejectsLongTokens(t *testing.T) {
+	sm := NewSessionManager(WithIDValidator(func(string) bool { return true }))
+	t.Cleanup(func() { sm.Close() })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: sm.cookieName, Value: strings.Repeat("a", maxTokenLength)})
+	_, ok := sm.readID(req)
+	assert.True(t, ok)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: sm.cookieName, Value: strings.Repeat("a", maxTokenLength+1)})
+	_, ok = sm.readID(req)
+	assert.False(t, ok)
+	assert.True(t, sm.hasCookie(req))
+}
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// WithGCJitter delays every garbage collection by a random part of up to fraction of the collection
+// interval, so replicas sharing a remote store do not collect at the same time. fraction is between 0 and 1.
+func WithGCJitter(fraction float64) Option {
+	return func(s *SessionManager) {
+		if fraction < 0 || fraction > 1 {
+			panic(fmt.Sprintf("session: gc jitter must be between 0 and 1, got %v", fraction))
+		}
+		s.gcJitter = fraction
+	}
+}
+
+// jitter returns a random delay of up to fraction of interval.
+func jitter(interval time.Duration, fraction float64) time.Duration {
+	if fraction == 0 || interval <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(float64(interval)*fraction) + 1))
+}
+
+// GCStats describes a garbage collection.
+type GCStats struct {
+	// Scanned is the number of stored sessions when the collection started, -1 if the store cannot count them.
+	Scanned int
+	// Deleted is the number of expired sessions removed.
+	Deleted int
+	// Took is the duration of the collection, including the expiration hooks.
+	Took time.Duration
+	// Err is the error of the store, if the collection failed.
+	Err error
+}
+
+// WithGCObserver calls observe with the statistics of every garbage collection, e.g. to chart how many
+// sessions expire. It runs on the collecting goroutine, so it should return quickly.
+func WithGCObserver(observe func(GCStats)) Option {
+	return func(s *SessionManager) {
+		s.gcObserver = observe
+	}
+}
+
+// RunGC removes the expired sessions now, independent of the validation ticker, and returns the
+// statistics of the collection. With WithGCBudget it stops when the budget is exhausted.
+func (m *SessionManager) RunGC(ctx context.Context) (GCStats, error) {
+	if err := ctx.Err(); err != nil {
+		return GCStats{}, err
+	}
+	var deleted atomic.Int64
+	start := time.Now()
+	stats := GCStats{Scanned: storeCount(m.store)}
+	span := m.startSpan(ctx, "session.gc")
+	err := m.store.gc(m.idleExpiration, m.absoluteExpiration, func(session *Session) {
+		deleted.Add(1)
+		m.expired(session)
+	})
+	stats.Deleted = int(deleted.Load())
+	stats.Took = time.Since(start)
+	stats.Err = err
+	span.set("session.scanned", stats.Scanned)
+	span.set("session.deleted", stats.Deleted)
+	span.end(err)
+	if m.invalidIDs != nil {
+		m.invalidIDs.prune()
+	}
+	if m.gcObserver != nil {
+		m.gcObserver(stats)
+	}
+	return stats, err
+}
+
+// GCLeader elects the instance collecting garbage when replicas share a remote store, e.g. with a
+// lease key set if absent with an expiry in the store's backend.
+type GCLeader interface {
+	// Lead reports whether this instance runs the collection of the current interval. A lease taken
+	// should expire after interval, so another instance takes over when the leader is gone.
+	Lead(ctx context.Context, interval time.Duration) (bool, error)
+}
+
+// WithGCLeader runs the scheduled garbage collections only on the instance elected by leader, instead
+// of on every replica. RunGC and the final collection of Close are not affected.
+func WithGCLeader(leader GCLeader) Option {
+	return func(s *SessionManager) {
+		s.gcLeader = leader
+	}
+}
+
+// leadsGC reports whether this instance runs the scheduled collection. Instances that do not still
+// prune their local state. If the election fails the instance collects, as duplicate collections are
+// harmless while skipped ones let expired sessions pile up.
+func (m *SessionManager) leadsGC(interval time.Duration) bool {
+	if m.gcLeader == nil {
+		return true
+	}
+	lead, err := m.gcLeader.Lead(context.Background(), interval)
+	if err != nil {
+		m.logger.Error("session gc leader election failed", "error", err)
+		return true
+	}
+	if !lead && m.invalidIDs != nil {
+		m.invalidIDs.prune()
+	}
+	return lead
+}
+
+// WithGCBudget limits a single garbage collection to maxDuration and maxDeletes removed sessions,
+// zero meaning no limit, so a collection cannot stall request handling. Expired sessions left over
+// are removed by the next collections. It applies to stores that support it, such as the in-memory store.
+func WithGCBudget(maxDuration time.Duration, maxDeletes int) Option {
+	return func(s *SessionManager) {
+		s.gcMaxDuration = maxDuration
+		s.gcMaxDeletes = maxDeletes
+	}
+}
+
+// gcBudgeter is implemented by stores that can stop a garbage collection early, see WithGCBudget.
+type gcBudgeter interface {
+	setGCBudget(maxDuration time.Duration, maxDeletes int)
+}
+
+// useGCBudget passes the budget of WithGCBudget to the store.
+func (m *SessionManager) useGCBudget() {
+	if m.gcMaxDuration <= 0 && m.gcMaxDeletes <= 0 {
+		return
+	}
+	if store, ok := m.store.(gcBudgeter); ok {
+		store.setGCBudget(m.gcMaxDuration, m.gcMaxDeletes)
+	}
+}
+
+// gcBudget tracks the limits of WithGCBudget during one collection.
+type gcBudget struct {
+	deadline   time.Time
+	deletes    int
+	maxDeletes int
+}
+
+func newGCBudget(maxDuration time.Duration, maxDeletes int) *gcBudget {
+	b := &gcBudget{maxDeletes: maxDeletes}
+	if maxDuration > 0 {
+		b.deadline = time.Now().Add(maxDuration)
+	}
+	return b
+}
+
+// exhausted reports whether the collection has to stop.
+func (b *gcBudget) exhausted() bool {
+	return (b.maxDeletes > 0 && b.deletes >= b.maxDeletes) ||
+		(!b.deadline.IsZero() && time.Now().After(b.deadline))
+}
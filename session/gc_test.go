@@ -0,0 +1,103 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunGC(t *testing.T) {
+	store := NewInMemorySessionStore()
+	var expired int
+	var observed []GCStats
+	sm := NewSessionManager(
+		WithStore(store),
+		WithIdleExpiration(time.Minute),
+		WithHooks(Hooks{OnExpire: func(*Session) { expired++ }}),
+		WithGCObserver(func(stats GCStats) { observed = append(observed, stats) }),
+	)
+	t.Cleanup(func() { sm.Close() })
+	for i := range 3 {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		if i > 0 {
+			sess.setLastActivity(time.Now().Add(-time.Hour))
+		}
+		assert.NoError(t, store.write(sess))
+	}
+
+	stats, err := sm.RunGC(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Scanned)
+	assert.Equal(t, 2, stats.Deleted)
+	assert.Positive(t, stats.Took)
+	assert.Equal(t, 2, expired)
+	assert.Equal(t, 1, store.count())
+	assert.Equal(t, []GCStats{stats}, observed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = sm.RunGC(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGCJitter(t *testing.T) {
+	assert.Panics(t, func() { NewSessionManager(WithGCJitter(1.5)) })
+	assert.Zero(t, jitter(time.Minute, 0))
+	for range 100 {
+		delay := jitter(time.Minute, 0.1)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, 6*time.Second)
+	}
+}
+
+// lease elects the first candidate asking and keeps it leading, like a lease renewed by its holder.
+type lease struct {
+	mu     sync.Mutex
+	holder int
+	leads  map[int]int
+}
+
+type leaseCandidate struct {
+	lease *lease
+	id    int
+}
+
+func (c leaseCandidate) Lead(context.Context, time.Duration) (bool, error) {
+	c.lease.mu.Lock()
+	defer c.lease.mu.Unlock()
+	if c.lease.holder == 0 {
+		c.lease.holder = c.id
+	}
+	if c.lease.holder != c.id {
+		return false, nil
+	}
+	c.lease.leads[c.id]++
+	return true, nil
+}
+
+func TestGCLeader(t *testing.T) {
+	shared := NewInMemorySessionStore()
+	var collections atomic.Int32
+	l := &lease{leads: make(map[int]int)}
+	for id := 1; id <= 3; id++ {
+		sm := NewSessionManager(
+			WithStore(shared),
+			WithValidationTicker(time.NewTicker(5*time.Millisecond)),
+			WithGCObserver(func(GCStats) { collections.Add(1) }),
+			WithGCLeader(leaseCandidate{lease: l, id: id}),
+		)
+		t.Cleanup(func() { sm.Close() })
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	assert.Len(t, l.leads, 1)
+	assert.Positive(t, collections.Load())
+	assert.LessOrEqual(t, collections.Load(), int32(l.leads[l.holder]))
+}
@@ -0,0 +1,45 @@
+package session
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithVaryCookie controls whether "Cookie" is added to the Vary header of responses, which is the default.
+func WithVaryCookie(vary bool) Option {
+	return func(s *SessionManager) {
+		s.varyCookie = vary
+	}
+}
+
+// WithCacheControl sets the Cache-Control header of responses without one. The default is
+// no-cache="Set-Cookie", so shared caches do not store the cookie; an empty value disables the header.
+func WithCacheControl(value string) Option {
+	return func(s *SessionManager) {
+		s.cacheControl = value
+	}
+}
+
+// setCacheHeaders keeps caches from serving a response to other sessions.
+// Headers set by other middleware are extended, never overwritten.
+func (m *SessionManager) setCacheHeaders(h http.Header) {
+	if m.varyCookie && !varies(h, "Cookie") {
+		h.Add("Vary", "Cookie")
+	}
+	if m.cacheControl != "" && h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", m.cacheControl)
+	}
+}
+
+// varies reports whether the Vary header already lists field.
+func varies(h http.Header, field string) bool {
+	for _, value := range h.Values("Vary") {
+		for _, f := range strings.Split(value, ",") {
+			f = strings.TrimSpace(f)
+			if f == "*" || strings.EqualFold(f, field) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		before       gin.HandlerFunc
+		vary         []string
+		cacheControl string
+	}{
+		{"default", nil, nil, []string{"Cookie"}, `no-cache="Set-Cookie"`},
+		{"disabled", []Option{WithVaryCookie(false), WithCacheControl("")}, nil, nil, ""},
+		{"custom", []Option{WithCacheControl("private")}, nil, []string{"Cookie"}, "private"},
+		{"appended", nil, func(c *gin.Context) {
+			c.Header("Vary", "Accept-Encoding")
+			c.Header("Cache-Control", "no-store")
+		}, []string{"Accept-Encoding", "Cookie"}, "no-store"},
+		{"already varies", nil, func(c *gin.Context) {
+			c.Header("Vary", "Accept, cookie")
+		}, []string{"Accept, cookie"}, `no-cache="Set-Cookie"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewSessionManager(tt.opts...)
+			t.Cleanup(func() { sm.Close() })
+			router := gin.New()
+			if tt.before != nil {
+				router.Use(tt.before)
+			}
+			router.Use(sm.Handle())
+			router.GET("/", func(c *gin.Context) {})
+
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, tt.vary, rw.Header().Values("Vary"))
+			assert.Equal(t, tt.cacheControl, rw.Header().Get("Cache-Control"))
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHijackPersistsSession(t *testing.T) {
+	upgrade := func(w http.ResponseWriter, sess *Session) {
+		sess.Put("handshake", true)
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		assert.NoError(t, err)
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+	}
+
+	tests := []struct {
+		name    string
+		handler func(sm *SessionManager, ids chan<- string) http.Handler
+	}{
+		{"gin", func(sm *SessionManager, ids chan<- string) http.Handler {
+			router := gin.New()
+			router.Use(sm.Handle())
+			router.GET("/ws", func(c *gin.Context) {
+				sess := GetSession(c)
+				upgrade(c.Writer, sess)
+				ids <- sess.ID()
+			})
+			return router
+		}},
+		{"net/http", func(sm *SessionManager, ids chan<- string) http.Handler {
+			return sm.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sess, _ := FromContext(r.Context())
+				upgrade(w, sess)
+				ids <- sess.ID()
+			}))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewInMemorySessionStore()
+			sm := NewSessionManager(WithStore(store))
+			ids := make(chan string, 1)
+			srv := httptest.NewServer(tt.handler(sm, ids))
+			defer srv.Close()
+
+			conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+			assert.NoError(t, err)
+			defer conn.Close()
+			_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+			assert.NoError(t, err)
+
+			status, err := bufio.NewReader(conn).ReadString('\n')
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(status, "HTTP/1.1 101"))
+			assert.Equal(t, true, store.read(<-ids).Get("handshake"))
+		})
+	}
+}
+
+func TestHijackReleasesLock(t *testing.T) {
+	sm := NewSessionManager(WithSessionLocking(true))
+	t.Cleanup(func() { sm.Close() })
+	hijacked, release := make(chan struct{}), make(chan struct{})
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/count", func(c *gin.Context) {
+		count, _ := GetGenericValue[int](GetSession(c), "count")
+		GetSession(c).Put("count", count+1)
+	})
+	router.GET("/ws", func(c *gin.Context) {
+		conn, _, err := c.Writer.Hijack()
+		assert.NoError(t, err)
+		defer conn.Close()
+		close(hijacked)
+		<-release
+	})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/count")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	cookie := resp.Cookies()[0]
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: localhost\r\nCookie: session=" + cookie.Value + "\r\n\r\n"))
+	assert.NoError(t, err)
+	<-hijacked
+
+	// The connection is still open, but the session is not locked anymore.
+	req := httptest.NewRequest(http.MethodGet, "/count", nil)
+	req.AddCookie(cookie)
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request blocked by the hijacked connection")
+	}
+	close(release)
+	assert.Eventually(t, func() bool {
+		sm.locker.mu.Lock()
+		defer sm.locker.mu.Unlock()
+		return len(sm.locker.locks) == 0
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,53 @@
+package session
+
+// Hooks are called on session lifecycle events. All fields are optional. Hooks run synchronously
+// on the request or garbage collection goroutine, so they should return quickly.
+type Hooks struct {
+	// OnCreate is called after a new session has been saved for the first time.
+	OnCreate func(session *Session)
+	// OnDestroy is called after a session has been removed by SessionManager.Destroy.
+	OnDestroy func(session *Session)
+	// OnExpire is called for sessions removed because of their idle or absolute expiration.
+	OnExpire func(session *Session)
+	// OnRegenerate is called after SessionManager.Regenerate moved a session from oldID to a new id.
+	OnRegenerate func(oldID string, session *Session)
+	// OnInvalidID is called for requests carrying an unknown or invalid session id, with the client IP
+	// and the number of such requests it sent within the window of WithInvalidIDLimit (a minute by default).
+	OnInvalidID func(ip string, attempts int)
+}
+
+func WithHooks(hooks Hooks) Option {
+	return func(s *SessionManager) {
+		s.hooks = hooks
+	}
+}
+
+func (h Hooks) create(session *Session) {
+	if h.OnCreate != nil {
+		h.OnCreate(session)
+	}
+}
+
+func (h Hooks) destroy(session *Session) {
+	if h.OnDestroy != nil {
+		h.OnDestroy(session)
+	}
+}
+
+func (h Hooks) expire(session *Session) {
+	if h.OnExpire != nil {
+		h.OnExpire(session)
+	}
+}
+
+func (h Hooks) regenerate(oldID string, session *Session) {
+	if h.OnRegenerate != nil {
+		h.OnRegenerate(oldID, session)
+	}
+}
+
+func (h Hooks) invalidID(ip string, attempts int) {
+	if h.OnInvalidID != nil {
+		h.OnInvalidID(ip, attempts)
+	}
+}
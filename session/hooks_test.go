@@ -0,0 +1,68 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	var events []string
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(
+		WithStore(store),
+		WithHooks(Hooks{
+			OnCreate:     func(*Session) { events = append(events, "create") },
+			OnDestroy:    func(*Session) { events = append(events, "destroy") },
+			OnExpire:     func(*Session) { events = append(events, "expire") },
+			OnRegenerate: func(string, *Session) { events = append(events, "regenerate") },
+		}),
+	)
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/create", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+	router.GET("/regenerate", func(c *gin.Context) {
+		sess, err := sm.Regenerate(c)
+		assert.NoError(t, err)
+		assert.Equal(t, "value", sess.Get("key"))
+	})
+	router.GET("/destroy", func(c *gin.Context) {
+		assert.NoError(t, sm.Destroy(c))
+	})
+
+	do := func(path string, cookie *http.Cookie) *http.Cookie {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		if cookies := rw.Result().Cookies(); len(cookies) > 0 {
+			return cookies[0]
+		}
+		return nil
+	}
+
+	cookie := do("/create", nil)
+	regenerated := do("/regenerate", cookie)
+	assert.NotEqual(t, cookie.Value, regenerated.Value)
+	assert.Nil(t, store.read(cookie.Value))
+	do("/destroy", regenerated)
+	assert.Nil(t, store.read(regenerated.Value))
+	assert.Equal(t, []string{"create", "regenerate", "destroy"}, events)
+
+	events = nil
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+	assert.NoError(t, sm.save(sess, nil))
+	sess.createdAt = time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, sm.Close())
+	assert.Equal(t, []string{"create", "expire"}, events)
+}
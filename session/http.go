@@ -0,0 +1,118 @@
+package session
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// responseWriter is the net/http counterpart of sessionContextWriter.
+type responseWriter struct {
+	http.ResponseWriter
+	cookieWriter
+}
+
+// WithHTTPErrorHandler is the WithErrorHandler counterpart for Middleware. If it is not set,
+// errors are logged and requests that cannot be served without a session get a 500.
+func WithHTTPErrorHandler(handler func(http.ResponseWriter, *http.Request, error)) Option {
+	return func(s *SessionManager) {
+		s.httpErrorHandler = handler
+	}
+}
+
+// Middleware provides the sessions of Handle to net/http handlers. Both share the store and the
+// cookie, so gin and net/http services can be mixed. Use FromContext to access the session.
+// The skipper and the gin error handler are not used by Middleware. As net/http has no
+// equivalent of c.Abort, WithSaveOnAbort only applies to panicking handlers.
+func (m *SessionManager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, state := m.attach(r)
+
+		// Serialize requests of the same session until it is saved
+		unlock := sync.OnceFunc(m.lock(r))
+		defer unlock()
+
+		// Start the session
+		session, err := m.start(r, state)
+		if err != nil {
+			if m.httpErrorHandler != nil {
+				m.httpErrorHandler(w, r, err)
+			} else {
+				m.logger.Error("starting session failed", "error", err)
+				http.Error(w, http.StatusText(errorStatus(err)), errorStatus(err))
+			}
+			return
+		}
+
+		// Wrap the response writer so the cookie is written before the response is flushed
+		rw := &responseWriter{
+			ResponseWriter: w,
+			cookieWriter: cookieWriter{
+				sessionManager: m,
+				state:          state,
+				unlock:         unlock,
+			},
+		}
+		// Add essential headers
+		m.setCacheHeaders(rw.Header())
+
+		// Persist or roll back the session of panicking handlers before passing the panic on
+		sn := m.takeSnapshot(session)
+		defer func() {
+			if rec := recover(); rec != nil {
+				if sn != nil {
+					m.rollback(state, sn)
+				} else if err := m.finish(state); err != nil {
+					m.logger.Error("saving session failed", "error", err)
+				}
+				panic(rec)
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+
+		// Write the session cookie to the response if no handler wrote a response
+		rw.writeCookieIfNecessary(w)
+
+		err = m.finish(state)
+
+		if err != nil {
+			if m.httpErrorHandler != nil {
+				m.httpErrorHandler(w, r, err)
+			} else {
+				m.logger.Error("saving session failed", "error", err)
+			}
+		}
+	})
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.writeCookieIfNecessary(w.ResponseWriter)
+	w.sent = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.writeCookieIfNecessary(w.ResponseWriter)
+	w.sent = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Flush() {
+	w.writeCookieIfNecessary(w.ResponseWriter)
+	w.sent = true
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked()
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,42 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	handler := sm.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess, ok := FromContext(r.Context())
+		assert.True(t, ok)
+		count, _ := GetGenericValue[int](sess, "count")
+		sess.Put("count", count+1)
+		w.Write([]byte("done"))
+	}))
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rw.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "Cookie", rw.Header().Get("Vary"))
+
+	// gin handlers share the store and cookie
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		assert.Equal(t, 1, sess.Get("count"))
+		sess.Put("count", 2)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, 2, store.read(cookies[0].Value).Get("count"))
+}
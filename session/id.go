@@ -0,0 +1,158 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// minIDLength is the smallest number of random bytes accepted by WithIDLength (128 bits).
+const minIDLength = 16
+
+// WithIDGenerator replaces the random 32 byte session ids, e.g. with UUIDv7 or prefixed ids.
+// An error returned by generate is passed to the error handler instead of starting a session.
+// Incoming ids are not validated unless WithIDValidator is set as well.
+func WithIDGenerator(generate func() (string, error)) Option {
+	return func(s *SessionManager) {
+		s.idGenerator = generate
+	}
+}
+
+// WithIDLength sets the number of random bytes of generated session ids. The default is 32.
+// It panics if n is less than 16.
+func WithIDLength(n int) Option {
+	return func(s *SessionManager) {
+		if n < minIDLength {
+			panic(fmt.Errorf("session id length must be at least %d bytes, got %d", minIDLength, n))
+		}
+		s.idLength = n
+	}
+}
+
+// WithIDValidator rejects incoming session ids for which valid returns false without asking the store.
+// Random ids are validated by default; use this to validate ids from WithIDGenerator.
+func WithIDValidator(valid func(id string) bool) Option {
+	return func(s *SessionManager) {
+		s.idValidator = valid
+	}
+}
+
+// useRandomIDs generates ids of idLength random bytes and, unless a validator is set,
+// only accepts ids of exactly that format.
+func (m *SessionManager) useRandomIDs() {
+	n := m.idLength
+	m.idGenerator = func() (string, error) {
+		return randomID(n)
+	}
+	if m.idValidator == nil {
+		m.idValidator = func(id string) bool {
+			return isRandomID(id, n)
+		}
+	}
+}
+
+// isRandomID reports whether id is the unpadded base64url encoding of n bytes.
+func isRandomID(id string, n int) bool {
+	if len(id) != base64.RawURLEncoding.EncodedLen(n) {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// WithHashedIDs keys the store by the SHA-256 hash of the session id instead of the id itself,
+// so the contents of a leaked store cannot be used as cookies. Sessions stored before
+// the option was enabled are no longer found.
+func WithHashedIDs(hash bool) Option {
+	return func(s *SessionManager) {
+		s.hashIDs = hash
+	}
+}
+
+// storeKey returns the key of the session with the given id in the store.
+func (m *SessionManager) storeKey(id string) string {
+	if !m.hashIDs {
+		return id
+	}
+	return hashID(id)
+}
+
+// storeKey returns the key of the session in the store.
+func (s *Session) storeKey() string {
+	if s.key != "" {
+		return s.key
+	}
+	return s.id
+}
+
+// read loads the session with the given id from the store.
+func (m *SessionManager) read(id string) *Session {
+	session, _ := m.readChecked(id)
+	return session
+}
+
+// readChecked is read reporting corrupt sessions, which it destroys, with an error wrapping ErrCorruptSession.
+func (m *SessionManager) readChecked(id string) (*Session, error) {
+	return m.readKey(m.storeKey(id), id)
+}
+
+// readKey is readChecked by store key. id is the session id stored under key, or empty if unknown.
+func (m *SessionManager) readKey(key, id string) (*Session, error) {
+	read := func() (*Session, error) {
+		session, err := readStore(m.store, key)
+		if errors.Is(err, ErrCorruptSession) {
+			m.quarantine(key, err)
+			return nil, err
+		}
+		if session != nil && session.clock == nil {
+			// sessions decoded by persistent stores
+			session.clock = m.clock
+		}
+		if session != nil && id != "" && session.id != id {
+			// persistent stores only know the hash
+			session.id = id
+			session.key = key
+		}
+		if session != nil && len(m.migrations) > 0 && !m.upgrade(session) {
+			return nil, nil
+		}
+		return session, err
+	}
+	if m.reads != nil {
+		return m.reads.do(key, read)
+	}
+	return read()
+}
+
+// newSession creates a fresh session with an id of the configured generator.
+func (m *SessionManager) newSession() (*Session, error) {
+	var session *Session
+	if m.pooling {
+		id, err := m.idGenerator()
+		if err != nil {
+			return nil, err
+		}
+		session = acquireSession(id, m.clock)
+	} else {
+		var err error
+		session, err = newSession(m.idGenerator)
+		if err != nil {
+			return nil, err
+		}
+		if m.clock != nil {
+			session.clock = m.clock
+			session.createdAt = m.now()
+			session.touch()
+		}
+	}
+	if m.hashIDs {
+		session.key = m.storeKey(session.id)
+	}
+	session.schema = len(m.migrations)
+	return session, nil
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIDGenerator(t *testing.T) {
+	n := 0
+	cookie := issueCookie(t, WithIDGenerator(func() (string, error) {
+		n++
+		return fmt.Sprintf("sess_%d", n), nil
+	}))
+	assert.Equal(t, "sess_1", cookie.Value)
+}
+
+func TestIDGeneratorError(t *testing.T) {
+	errRand := errors.New("entropy exhausted")
+	var handled []error
+	sm := NewSessionManager(
+		WithIDGenerator(func() (string, error) { return "", errRand }),
+		WithErrorHandler(func(c *gin.Context, err error) {
+			handled = append(handled, err)
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+		}),
+	)
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		t.Error("handler must not run without a session")
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Len(t, handled, 1)
+	assert.ErrorIs(t, handled[0], errRand)
+}
+
+func TestIDLength(t *testing.T) {
+	cookie := issueCookie(t, WithIDLength(16))
+	assert.Len(t, cookie.Value, 22)
+	assert.Len(t, issueCookie(t).Value, 43)
+	assert.Panics(t, func() { NewSessionManager(WithIDLength(8)) })
+}
+
+func TestIDValidation(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	_, ok := sm.decodeID(sess.id)
+	assert.True(t, ok)
+
+	for _, id := range []string{"short", sess.id + "x", sess.id[:42] + "*", "key:*"} {
+		_, ok := sm.decodeID(id)
+		assert.False(t, ok, id)
+	}
+
+	custom := NewSessionManager(
+		WithIDGenerator(func() (string, error) { return "sess_1", nil }),
+		WithIDValidator(func(id string) bool { return len(id) > 5 && id[:5] == "sess_" }),
+	)
+	t.Cleanup(func() { custom.Close() })
+	_, ok = custom.decodeID("sess_1")
+	assert.True(t, ok)
+	_, ok = custom.decodeID(sess.id)
+	assert.False(t, ok)
+}
+
+func TestHashedIDs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sessions.json")
+	sm := NewSessionManager(WithStore(NewFileStore(file)), WithHashedIDs(true))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		count, _ := GetGenericValue[float64](sess, "count")
+		sess.Put("count", count+1)
+		c.String(http.StatusOK, sess.ID())
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookie := rw.Result().Cookies()[0]
+	assert.Equal(t, cookie.Value, rw.Body.String())
+
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(string(data), cookie.Value))
+	assert.True(t, strings.Contains(string(data), sm.storeKey(cookie.Value)))
+
+	rw = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, cookie.Value, rw.Body.String())
+	assert.Equal(t, 2.0, sm.read(cookie.Value).Get("count"))
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonatorIDKey is the session key under which Impersonate stores the id of the impersonating user.
+const ImpersonatorIDKey = "session.impersonator_id"
+
+// impersonatorKeyPrefix prefixes the auth keys of the impersonator while they are set aside.
+const impersonatorKeyPrefix = "session.impersonator."
+
+var (
+	// ErrNotLoggedIn is returned by Impersonate for sessions without a user.
+	ErrNotLoggedIn = errors.New("session is not logged in")
+	// ErrNotImpersonating is returned by StopImpersonating for sessions that do not impersonate a user.
+	ErrNotImpersonating = errors.New("session is not impersonating a user")
+)
+
+// Impersonate lets the logged in user, e.g. a support agent, act as targetUserID. UserIDKey is set to
+// the target and the auth keys of WithAuthKeys are set aside until StopImpersonating restores them.
+// The session id is regenerated and the session stays in the index of the impersonator.
+func (m *SessionManager) Impersonate(c *gin.Context, targetUserID any) error {
+	err := m.ImpersonateContext(c.Request.Context(), targetUserID)
+	if err != nil {
+		return err
+	}
+	session, _ := SessionFrom(c)
+	m.mirror(c, session)
+	return nil
+}
+
+// ImpersonateContext is Impersonate for the request context of Middleware.
+func (m *SessionManager) ImpersonateContext(ctx context.Context, targetUserID any) error {
+	state := stateFrom(ctx)
+	session, ok := state.get()
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if _, ok := session.Impersonator(); ok {
+		// Switching the target keeps the original identity.
+		err := m.StopImpersonatingContext(ctx)
+		if err != nil {
+			return err
+		}
+		session, _ = state.get()
+	}
+	userID, ok := session.load(UserIDKey)
+	if !ok {
+		return ErrNotLoggedIn
+	}
+	session, err := m.RegenerateContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range m.authKeys {
+		if value, ok := session.load(key); ok {
+			session.Put(impersonatorKeyPrefix+key, value)
+			session.Delete(key)
+		}
+	}
+	session.Put(ImpersonatorIDKey, userID)
+	session.Put(UserIDKey, targetUserID)
+	m.audit(AuditImpersonateStart, session, state.request)
+	return nil
+}
+
+// StopImpersonating ends Impersonate and restores the identity and auth keys of the impersonator.
+func (m *SessionManager) StopImpersonating(c *gin.Context) error {
+	err := m.StopImpersonatingContext(c.Request.Context())
+	if err != nil {
+		return err
+	}
+	session, _ := SessionFrom(c)
+	m.mirror(c, session)
+	return nil
+}
+
+// StopImpersonatingContext is StopImpersonating for the request context of Middleware.
+func (m *SessionManager) StopImpersonatingContext(ctx context.Context) error {
+	state := stateFrom(ctx)
+	session, ok := state.get()
+	if !ok {
+		return ErrSessionNotFound
+	}
+	userID, ok := session.Impersonator()
+	if !ok {
+		return ErrNotImpersonating
+	}
+	m.audit(AuditImpersonateStop, session, state.request)
+	session, err := m.RegenerateContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range m.authKeys {
+		session.Delete(key)
+		if value, ok := session.load(impersonatorKeyPrefix + key); ok {
+			session.Put(key, value)
+			session.Delete(impersonatorKeyPrefix + key)
+		}
+	}
+	session.Put(UserIDKey, userID)
+	session.Delete(ImpersonatorIDKey)
+	return nil
+}
+
+// Impersonator returns the id of the user impersonating the session's user, see SessionManager.Impersonate.
+func (s *Session) Impersonator() (any, bool) {
+	return s.load(ImpersonatorIDKey)
+}
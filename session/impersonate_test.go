@@ -0,0 +1,75 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonate(t *testing.T) {
+	var events []AuditEvent
+	sm := NewSessionManager(
+		WithAuthKeys("role"),
+		WithAuditSink(AuditFunc(func(event AuditEvent) { events = append(events, event) })),
+	)
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/login", func(c *gin.Context) {
+		assert.NoError(t, sm.Login(c, "admin"))
+		GetSession(c).Put("role", "admin")
+	})
+	router.GET("/impersonate", func(c *gin.Context) {
+		assert.NoError(t, sm.Impersonate(c, "alice"))
+	})
+	router.GET("/stop", func(c *gin.Context) {
+		assert.NoError(t, sm.StopImpersonating(c))
+	})
+	router.GET("/whoami", func(c *gin.Context) {
+		sess := GetSession(c)
+		impersonator, _ := sess.Impersonator()
+		c.JSON(http.StatusOK, []any{sess.Get(UserIDKey), sess.Get("role"), impersonator})
+	})
+
+	do := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	admin := do("/login", nil).Result().Cookies()[0]
+	impersonating := do("/impersonate", admin).Result().Cookies()[0]
+	assert.NotEqual(t, admin.Value, impersonating.Value)
+	assert.Equal(t, `["alice",null,"admin"]`, do("/whoami", impersonating).Body.String())
+
+	infos, err := sm.SessionsForUser(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "admin")
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+
+	restored := do("/stop", impersonating).Result().Cookies()[0]
+	assert.Equal(t, `["admin","admin",null]`, do("/whoami", restored).Body.String())
+	rw := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rw)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.ErrorIs(t, sm.StopImpersonating(c), ErrSessionNotFound)
+
+	var impersonations []AuditEvent
+	for _, event := range events {
+		if event.Type == AuditImpersonateStart || event.Type == AuditImpersonateStop {
+			impersonations = append(impersonations, event)
+		}
+	}
+	if assert.Len(t, impersonations, 2) {
+		assert.Equal(t, "alice", impersonations[0].UserID)
+		assert.Equal(t, "admin", impersonations[0].ImpersonatorID)
+		assert.Equal(t, AuditImpersonateStop, impersonations[1].Type)
+		assert.Equal(t, "admin", impersonations[1].ImpersonatorID)
+	}
+}
@@ -0,0 +1,150 @@
+package session
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Invalidator broadcasts the store keys of destroyed sessions to every instance sharing a store, so
+// instances drop copies they still hold in memory, e.g. queued by WithAsyncWrites or WithWriteCoalescing,
+// instead of writing a logged out session back. It is typically backed by a pub/sub channel such as
+// Redis PUBLISH/SUBSCRIBE; the channel must not be readable by clients, as keys are session ids
+// unless WithHashedIDs is used.
+type Invalidator interface {
+	// Publish sends key to the subscribers of every instance, including this one.
+	Publish(key string) error
+	// Subscribe calls handler for every published key until unsubscribe is called.
+	Subscribe(handler func(key string)) (unsubscribe func(), err error)
+}
+
+// WithInvalidator publishes every session destroyed by this manager with invalidator and drops the
+// sessions published by other instances from the local queues.
+func WithInvalidator(invalidator Invalidator) Option {
+	return func(s *SessionManager) {
+		s.invalidator = invalidator
+	}
+}
+
+// invalidater is implemented by stores holding sessions in memory in front of a shared store.
+type invalidater interface {
+	invalidate(key string)
+}
+
+// invalidate drops key from the local state of store and the stores it wraps.
+func invalidate(store SessionStore, key string) {
+	if s, ok := store.(invalidater); ok {
+		s.invalidate(key)
+	}
+}
+
+// useInvalidator wraps the store and subscribes for WithInvalidator. It panics if subscribing fails,
+// like the other configuration errors of NewSessionManager.
+func (m *SessionManager) useInvalidator() {
+	if m.invalidator == nil {
+		return
+	}
+	m.store = &invalidatingStore{inner: m.store, invalidator: m.invalidator, logger: m.logger}
+	store := m.store
+	unsubscribe, err := m.invalidator.Subscribe(func(key string) {
+		invalidate(store, key)
+	})
+	if err != nil {
+		panic(err)
+	}
+	m.unsubscribe = unsubscribe
+}
+
+// invalidatingStore publishes the keys of destroyed sessions.
+type invalidatingStore struct {
+	inner       SessionStore
+	invalidator Invalidator
+	logger      Logger
+}
+
+func (s *invalidatingStore) read(id string) *Session {
+	return s.inner.read(id)
+}
+
+func (s *invalidatingStore) readChecked(id string) (*Session, error) {
+	return readStore(s.inner, id)
+}
+
+func (s *invalidatingStore) write(session *Session) error {
+	return writeStore(s.inner, session)
+}
+
+func (s *invalidatingStore) destroy(id string) error {
+	err := s.inner.destroy(id)
+	if err != nil {
+		return err
+	}
+	if err := s.invalidator.Publish(id); err != nil {
+		// The session is gone from the shared store, other instances only keep their copies longer.
+		s.logger.Error("publishing session invalidation failed", "error", err)
+	}
+	return nil
+}
+
+func (s *invalidatingStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	return s.inner.gc(idleExpiration, absoluteExpiration, expired)
+}
+
+func (s *invalidatingStore) sharesSessions() bool {
+	return sharesSessions(s.inner)
+}
+
+func (s *invalidatingStore) invalidate(key string) {
+	invalidate(s.inner, key)
+}
+
+func (s *invalidatingStore) count() int {
+	return storeCount(s.inner)
+}
+
+func (s *invalidatingStore) unwrap() SessionStore {
+	return s.inner
+}
+
+// Close closes the inner store if it is an io.Closer.
+func (s *invalidatingStore) Close() error {
+	if closer, ok := s.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// localInvalidator delivers the published keys to the subscribers in the same process.
+type localInvalidator struct {
+	mu       sync.RWMutex
+	next     int
+	handlers map[int]func(key string)
+}
+
+// NewLocalInvalidator returns an Invalidator for managers in one process, e.g. several managers
+// sharing a store in tests. Instances in other processes need one backed by a pub/sub service.
+func NewLocalInvalidator() *localInvalidator {
+	return &localInvalidator{handlers: make(map[int]func(key string))}
+}
+
+func (i *localInvalidator) Publish(key string) error {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, handler := range i.handlers {
+		handler(key)
+	}
+	return nil
+}
+
+func (i *localInvalidator) Subscribe(handler func(key string)) (func(), error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	id := i.next
+	i.next++
+	i.handlers[id] = handler
+	return func() {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		delete(i.handlers, id)
+	}, nil
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidator(t *testing.T) {
+	shared := NewInMemorySessionStore()
+	invalidator := NewLocalInvalidator()
+	a := NewSessionManager(WithStore(shared), WithWriteCoalescing(time.Hour), WithInvalidator(invalidator))
+	b := NewSessionManager(WithStore(shared), WithAsyncWrites(10, time.Hour), WithInvalidator(invalidator))
+
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+	assert.NoError(t, shared.write(sess))
+
+	// Both instances hold a write of the session in memory when another one logs it out.
+	assert.NoError(t, a.save(sess, nil))
+	assert.NoError(t, b.save(sess, nil))
+	c := NewSessionManager(WithStore(shared), WithInvalidator(invalidator))
+	t.Cleanup(func() { c.Close() })
+	assert.NoError(t, c.store.destroy(sess.storeKey()))
+
+	assert.Nil(t, a.read(sess.id))
+	assert.Nil(t, b.read(sess.id))
+	assert.NoError(t, a.Shutdown(context.Background()))
+	assert.NoError(t, b.Shutdown(context.Background()))
+	assert.Nil(t, shared.read(sess.id))
+}
+
+func TestLocalInvalidatorUnsubscribe(t *testing.T) {
+	invalidator := NewLocalInvalidator()
+	var keys []string
+	unsubscribe, err := invalidator.Subscribe(func(key string) { keys = append(keys, key) })
+	assert.NoError(t, err)
+
+	assert.NoError(t, invalidator.Publish("a"))
+	unsubscribe()
+	assert.NoError(t, invalidator.Publish("b"))
+	assert.Equal(t, []string{"a"}, keys)
+}
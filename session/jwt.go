@@ -0,0 +1,87 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// jwtHeader is the encoded header of all tokens, {"alg":"HS256","typ":"JWT"}.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type jwtClaims struct {
+	SessionID string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// WithJWT wraps the session id in an HS256 JWT signed with the signing key, carrying the absolute
+// expiration of the session as "exp" and the id as "sid". Edge proxies knowing the key can reject
+// expired or forged tokens without asking the store. It requires WithSigningKey or WithSigningKeys,
+// older keys are accepted for verification.
+func WithJWT(enable bool) Option {
+	return func(s *SessionManager) {
+		s.jwt = enable
+	}
+}
+
+func (m *SessionManager) checkJWT() error {
+	if m.jwt && m.signingKeys == nil {
+		return errors.New("JWT tokens require a signing key")
+	}
+	return nil
+}
+
+// signJWT returns a token for sessionID issued at now.
+func signJWT(key []byte, sessionID string, now, expires time.Time) (string, error) {
+	claims, err := json.Marshal(jwtClaims{
+		SessionID: sessionID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + jwtSignature(key, unsigned), nil
+}
+
+// verifyJWT returns the session id of a token signed by one of keys and whether it is valid and unexpired at now.
+func verifyJWT(keys [][]byte, token string, now time.Time) (string, bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i <= len(jwtHeader) || !strings.HasPrefix(token, jwtHeader+".") {
+		return "", false
+	}
+	unsigned, signature := token[:i], token[i+1:]
+
+	valid := false
+	for _, key := range keys {
+		if hmac.Equal([]byte(signature), []byte(jwtSignature(key, unsigned))) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(unsigned[len(jwtHeader)+1:])
+	if err != nil {
+		return "", false
+	}
+	var claims jwtClaims
+	if json.Unmarshal(data, &claims) != nil || now.Unix() >= claims.ExpiresAt {
+		return "", false
+	}
+	return claims.SessionID, true
+}
+
+func jwtSignature(key []byte, unsigned string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
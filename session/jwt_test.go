@@ -0,0 +1,42 @@
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWT(t *testing.T) {
+	assert.Panics(t, func() { NewSessionManager(WithJWT(true)) })
+
+	key := []byte("secret")
+	cookie := issueCookie(t, WithJWT(true), WithSigningKey(key))
+	parts := strings.Split(cookie.Value, ".")
+	assert.Len(t, parts, 3)
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, err)
+	var claims jwtClaims
+	assert.NoError(t, json.Unmarshal(data, &claims))
+	assert.NotEmpty(t, claims.SessionID)
+	assert.Greater(t, claims.ExpiresAt, time.Now().Unix())
+
+	sid, ok := verifyJWT([][]byte{[]byte("other"), key}, cookie.Value, time.Now())
+	assert.True(t, ok)
+	assert.Equal(t, claims.SessionID, sid)
+	_, ok = verifyJWT([][]byte{[]byte("other")}, cookie.Value, time.Now())
+	assert.False(t, ok)
+
+	expired, err := signJWT(key, sid, time.Now(), time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	_, ok = verifyJWT([][]byte{key}, expired, time.Now())
+	assert.False(t, ok)
+
+	// alg none and other headers are rejected
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+	_, ok = verifyJWT([][]byte{key}, none, time.Now())
+	assert.False(t, ok)
+}
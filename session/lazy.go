@@ -0,0 +1,68 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// lazyData is the data of a session read from a decoding store, e.g. NewEncryptedStore. It is
+// decoded on first use, so requests which never touch their session skip the decoding, and
+// written back in its stored form as long as it is not changed.
+type lazyData struct {
+	once sync.Once
+	// stored is the data as the inner store returned it.
+	stored  map[string]any
+	decode  func() map[string]any
+	changed atomic.Bool
+}
+
+// withLazyData returns a copy of stored whose data is the result of decode, which is called on
+// first access of the data.
+func (s *Session) withLazyData(decode func() map[string]any) *Session {
+	session := s.withData(nil)
+	session.lazy = &lazyData{stored: s.values(), decode: decode}
+	return session
+}
+
+// resolve decodes lazy data. It must be called before the data or keys are read.
+func (s *Session) resolve() {
+	if s.lazy == nil {
+		return
+	}
+	s.lazy.once.Do(func() {
+		data := s.lazy.decode()
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		s.keys = keys
+		s.setData(data)
+	})
+}
+
+// storedForm returns the data the session was read with if it is still undecoded or unchanged.
+func (s *Session) storedForm() (map[string]any, bool) {
+	if s.lazy == nil || s.lazy.changed.Load() {
+		return nil, false
+	}
+	return s.lazy.stored, true
+}
+
+// checkObject returns an error unless data is a JSON object. Decoding stores validate their data
+// when it is read, so corrupt sessions are reported then and decodeObject cannot fail later.
+func checkObject(data []byte) error {
+	if !json.Valid(data) || !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return errors.New("session data is not a JSON object")
+	}
+	return nil
+}
+
+// decodeObject decodes data validated by checkObject.
+func decodeObject(data []byte) map[string]any {
+	values := make(map[string]any)
+	_ = json.Unmarshal(data, &values)
+	return values
+}
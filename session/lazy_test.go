@@ -0,0 +1,103 @@
+package session
+
+import (
+	"encoding/base64"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingCompressor struct {
+	compressed, decompressed atomic.Int32
+}
+
+func (c *countingCompressor) Compress(data []byte) ([]byte, error) {
+	c.compressed.Add(1)
+	return Gzip.Compress(data)
+}
+
+func (c *countingCompressor) Decompress(data []byte) ([]byte, error) {
+	c.decompressed.Add(1)
+	return Gzip.Decompress(data)
+}
+
+func TestLazyDecompression(t *testing.T) {
+	compressor := &countingCompressor{}
+	sm := NewSessionManager(WithCompression(compressor, 0))
+	t.Cleanup(func() { sm.Close() })
+
+	session, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	session.Put("cart", strings.Repeat("item,", 20))
+	assert.NoError(t, sm.save(session, nil))
+	assert.Equal(t, int32(1), compressor.compressed.Load())
+
+	read := sm.read(session.id)
+	assert.NotNil(t, read)
+	assert.NoError(t, sm.save(read, nil))
+	assert.Equal(t, int32(1), compressor.decompressed.Load())
+	assert.Equal(t, int32(1), compressor.compressed.Load())
+
+	read = sm.read(session.id)
+	assert.Equal(t, strings.Repeat("item,", 20), read.Get("cart"))
+	assert.Equal(t, []string{"cart"}, read.Keys())
+	assert.Equal(t, int32(2), compressor.decompressed.Load())
+	assert.NoError(t, sm.save(read, nil))
+	assert.Equal(t, int32(1), compressor.compressed.Load())
+
+	read.Put("count", 1)
+	assert.NoError(t, sm.save(read, nil))
+	assert.Equal(t, int32(2), compressor.compressed.Load())
+	assert.Equal(t, float64(1), sm.read(session.id).Get("count"))
+}
+
+func TestLazyDecompressionCorrupt(t *testing.T) {
+	inner := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(inner), WithCompression(Gzip, 0))
+	t.Cleanup(func() { sm.Close() })
+
+	for name, data := range map[string]string{"truncated": `{"cart":"item"}`, "not an object": `null`} {
+		compressed, err := Gzip.Compress([]byte(data))
+		assert.NoError(t, err)
+		if name == "truncated" {
+			compressed = compressed[:len(compressed)-4]
+		}
+		stored, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		stored.Put(compressedDataKey, base64.StdEncoding.EncodeToString(compressed))
+		assert.NoError(t, inner.write(stored))
+
+		_, err = sm.readChecked(stored.id)
+		assert.ErrorIs(t, err, ErrCorruptSession, name)
+		assert.Nil(t, inner.read(stored.id), "%s is quarantined", name)
+	}
+}
+
+func TestLazyDecryption(t *testing.T) {
+	oldKey, newKey := []byte("0123456789abcdef"), []byte("fedcba9876543210")
+	inner := NewInMemorySessionStore()
+	store, err := NewEncryptedStoreFromKeys(inner, StaticKeys(oldKey))
+	assert.NoError(t, err)
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+
+	session, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	session.Put("secret", "swordfish")
+	assert.NoError(t, sm.save(session, nil))
+	encrypted := inner.read(session.id).Get(encryptedDataKey)
+
+	read := sm.read(session.id)
+	assert.NoError(t, sm.save(read, nil))
+	assert.Equal(t, encrypted, inner.read(session.id).Get(encryptedDataKey))
+
+	rotated, err := NewEncryptedStoreFromKeys(inner, StaticKeys(newKey, oldKey))
+	assert.NoError(t, err)
+	read, err = rotated.readChecked(session.id)
+	assert.NoError(t, err)
+	assert.NoError(t, rotated.write(read))
+	assert.NotEqual(t, encrypted, inner.read(session.id).Get(encryptedDataKey))
+	assert.Equal(t, "swordfish", rotated.read(session.id).Get("secret"))
+}
@@ -0,0 +1,57 @@
+package session
+
+import "sync"
+
+// idLocker hands out one mutex per session id. Mutexes are dropped once nobody holds or waits for them.
+type idLocker struct {
+	mu    sync.Mutex
+	locks map[string]*idLock
+}
+
+type idLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newIDLocker() *idLocker {
+	return &idLocker{
+		locks: make(map[string]*idLock),
+	}
+}
+
+// lock blocks until the lock for id is acquired and returns the function releasing it.
+func (l *idLocker) lock(id string) func() {
+	l.mu.Lock()
+	lock, ok := l.locks[id]
+	if !ok {
+		lock = &idLock{}
+		l.locks[id] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, id)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// WithSessionLocking serializes requests carrying the same session cookie, so read-modify-write
+// sequences of parallel requests are not lost. The lock is held from loading until saving the session.
+func WithSessionLocking(enabled bool) Option {
+	return func(s *SessionManager) {
+		if enabled {
+			s.locker = newIDLocker()
+		} else {
+			s.locker = nil
+		}
+	}
+}
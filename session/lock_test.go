@@ -0,0 +1,42 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionLocking(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store), WithSessionLocking(true))
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/count", func(c *gin.Context) {
+		sess := GetSession(c)
+		count, _ := GetGenericValue[int](sess, "count")
+		sess.Put("count", count+1)
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/count", nil))
+	cookie := rw.Result().Cookies()[0]
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/count", nil)
+			req.AddCookie(cookie)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 51, store.read(cookie.Value).Get("count"))
+	assert.Empty(t, sm.locker.locks)
+}
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"log/slog"
+)
+
+// Logger receives the log output of the session manager and its stores; *slog.Logger implements it.
+// Arguments after the message are alternating keys and values as with slog.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// WithLogger sets the logger of the manager, by default slog.Default() is used.
+// Decisions about creating, loading, saving and destroying sessions are logged at debug level.
+func WithLogger(logger Logger) Option {
+	return func(s *SessionManager) {
+		s.logger = logger
+	}
+}
+
+// defaultLogger logs to slog.Default() at the time of the call, so slog.SetDefault is honored.
+type defaultLogger struct{}
+
+func (defaultLogger) Debug(msg string, args ...any) {
+	slog.Default().Debug(msg, append(args, "component", "gin-memory-sessions-go")...)
+}
+
+func (defaultLogger) Error(msg string, args ...any) {
+	slog.Default().Error(msg, append(args, "component", "gin-memory-sessions-go")...)
+}
+
+// logger is used by stores created outside of a manager until the manager passes its own.
+var logger Logger = defaultLogger{}
+
+// loggerSetter is implemented by stores which log, so they use the logger of the manager.
+type loggerSetter interface {
+	setLogger(logger Logger)
+}
+
+// useLogger passes the logger of the manager to the configured store.
+func (m *SessionManager) useLogger() {
+	if m.logger == nil {
+		m.logger = logger
+	}
+	if s, ok := m.store.(loggerSetter); ok {
+		s.setLogger(m.logger)
+	}
+}
+
+// logSession logs a debug message about a session, identified by the hash of its id.
+func (m *SessionManager) logSession(msg string, session *Session, args ...any) {
+	m.logger.Debug(msg, append([]any{"session", hashID(session.id)}, args...)...)
+}
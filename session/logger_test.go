@@ -0,0 +1,70 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	sm := NewSessionManager(WithLogger(logger))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+	router.GET("/destroy", func(c *gin.Context) {
+		assert.NoError(t, sm.Destroy(c))
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookie := rw.Result().Cookies()[0]
+	req := httptest.NewRequest(http.MethodGet, "/destroy", nil)
+	req.AddCookie(cookie)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := buf.String()
+	for _, msg := range []string{"session started", "session created", "session saved", "session loaded", "session destroyed"} {
+		assert.Contains(t, out, `msg="`+msg+`"`)
+	}
+	assert.Contains(t, out, "session="+hashID(cookie.Value))
+	assert.NotContains(t, out, cookie.Value+" ")
+}
+
+// failingStore fails every write.
+type failingStore struct {
+	*inMemorySessionStore
+	err error
+}
+
+func (s failingStore) write(*Session) error {
+	return s.err
+}
+
+func TestLoggerErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	sm := NewSessionManager(WithLogger(logger), WithStore(failingStore{inMemorySessionStore: NewInMemorySessionStore(), err: errors.New("store down")}))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Contains(t, buf.String(), "level=ERROR")
+	assert.Contains(t, buf.String(), "store down")
+	assert.NotContains(t, buf.String(), "level=DEBUG")
+}
@@ -0,0 +1,41 @@
+package session
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnknownSession is returned by Load for ids that are not in the store or have expired.
+var ErrUnknownSession = errors.New("unknown or expired session")
+
+// Load reads a session outside of a request, e.g. from background jobs, gRPC services or CLI tools.
+// Changes have to be persisted with Commit.
+func (m *SessionManager) Load(ctx context.Context, id string) (*Session, error) {
+	session := m.read(id)
+	if session == nil || !m.validate(session) {
+		return nil, ErrUnknownSession
+	}
+	return session, nil
+}
+
+// Create returns a new session that is persisted by Commit, even if no value was put.
+// Hand it to a client with the value of SessionToken, not its id.
+func (m *SessionManager) Create(ctx context.Context) (*Session, error) {
+	session, err := m.newSession()
+	if err != nil {
+		return nil, err
+	}
+	session.fresh = false
+	return session, nil
+}
+
+// SessionToken returns the cookie value or bearer token of session, signed, encrypted or wrapped in
+// a JWT as configured, e.g. to hand a session of Create to a client.
+func (m *SessionManager) SessionToken(session *Session) (string, error) {
+	return m.encodeID(session.id, session.createdAt.Add(m.absoluteExpiration))
+}
+
+// Commit persists a session obtained by Load or Create.
+func (m *SessionManager) Commit(ctx context.Context, session *Session) error {
+	return m.save(session, nil)
+}
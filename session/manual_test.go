@@ -0,0 +1,64 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualSessionControl(t *testing.T) {
+	ctx := context.Background()
+	sm := NewSessionManager()
+
+	_, err := sm.Load(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownSession)
+
+	sess, err := sm.Create(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, sm.Commit(ctx, sess))
+
+	loaded, err := sm.Load(ctx, sess.ID())
+	assert.NoError(t, err)
+	loaded.Put("job", "done")
+	assert.NoError(t, sm.Commit(ctx, loaded))
+
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		assert.Equal(t, "done", GetSession(c).Get("job"))
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: sess.ID()})
+	router.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestSessionToken(t *testing.T) {
+	ctx := context.Background()
+	sm := NewSessionManager(WithSigningKey([]byte("secret")))
+	t.Cleanup(func() { sm.Close() })
+
+	sess, err := sm.Create(ctx)
+	assert.NoError(t, err)
+	sess.Put("job", "done")
+	assert.NoError(t, sm.Commit(ctx, sess))
+	token, err := sm.SessionToken(sess)
+	assert.NoError(t, err)
+	assert.NotEqual(t, sess.ID(), token)
+
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "%v", GetSession(c).Get("job"))
+	})
+	for value, body := range map[string]string{token: "done", sess.ID(): "<nil>"} {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: value})
+		router.ServeHTTP(rw, req)
+		assert.Equal(t, body, rw.Body.String())
+	}
+}
@@ -0,0 +1,337 @@
+package session
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// StoreOption configures the store returned by NewInMemorySessionStore.
+type StoreOption func(*inMemorySessionStore)
+
+// WithShards sets the number of shards the sessions are spread over by a hash of their id.
+// Each shard has its own lock, so writes to different shards do not wait for each other. Defaults to 32.
+func WithShards(n int) StoreOption {
+	return func(s *inMemorySessionStore) {
+		if n < 1 {
+			panic(fmt.Sprintf("session: shard count must be positive, got %d", n))
+		}
+		s.shards = make([]*storeShard, n)
+	}
+}
+
+// WithMaxSessions caps the number of stored sessions at n. Beyond it the sessions written least
+// recently are evicted, so a traffic spike or a crawler cannot exhaust the memory. The cap is split
+// evenly across the shards, so eviction starts per shard and may set in before n sessions are stored.
+// A cap below the number of shards reduces the shards to n.
+func WithMaxSessions(n int) StoreOption {
+	return func(s *inMemorySessionStore) {
+		s.maxSessions = n
+	}
+}
+
+// WithMaxMemoryBytes caps the approximate memory used by the session data at n bytes, measured by
+// the JSON encoded size of each session when it is written. Beyond it the sessions written least
+// recently are evicted. Like WithMaxSessions the budget is split evenly across the shards.
+func WithMaxMemoryBytes(n int) StoreOption {
+	return func(s *inMemorySessionStore) {
+		s.maxBytes = n
+	}
+}
+
+// WithEvictionHandler is called with every session evicted by WithMaxSessions or WithMaxMemoryBytes,
+// e.g. to count evictions in a metric. It is called after the store was unlocked.
+func WithEvictionHandler(handler func(*Session)) StoreOption {
+	return func(s *inMemorySessionStore) {
+		s.onEvict = handler
+	}
+}
+
+type inMemorySessionStore struct {
+	shards      []*storeShard
+	maxSessions int
+	maxBytes    int
+	onEvict     func(*Session)
+
+	// gcMu serializes collections, which resume at the shard gcCursor when the last one ran out of budget.
+	gcMu          sync.Mutex
+	gcCursor      int
+	gcMaxDuration time.Duration
+	gcMaxDeletes  int
+	clock         Clock
+}
+
+type storeShard struct {
+	mu sync.RWMutex
+	// sessions maps ids to elements of recent, which holds the entries with the most recently written first.
+	sessions map[string]*list.Element
+	recent   *list.List
+	// idle and created order the entries by last activity and creation, so gc only visits expired ones.
+	idle        expiryQueue
+	created     expiryQueue
+	bytes       int
+	maxSessions int
+	maxBytes    int
+}
+
+// storeEntry is a stored session with its size at the time it was written.
+type storeEntry struct {
+	session *Session
+	size    int
+	// activity is the last activity of the session known to the idle queue. Reads move the
+	// actual one forward without a write, so it is refreshed when the entry looks expired.
+	activity     time.Time
+	idleIndex    int
+	createdIndex int
+}
+
+func NewInMemorySessionStore(opts ...StoreOption) *inMemorySessionStore {
+	s := &inMemorySessionStore{
+		shards: make([]*storeShard, 32),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// Every shard holds at least one session or byte, so the caps are never exceeded in total.
+	if s.maxSessions > 0 && s.maxSessions < len(s.shards) {
+		s.shards = s.shards[:s.maxSessions]
+	}
+	if s.maxBytes > 0 && s.maxBytes < len(s.shards) {
+		s.shards = s.shards[:s.maxBytes]
+	}
+	for i := range s.shards {
+		s.shards[i] = &storeShard{
+			sessions: make(map[string]*list.Element),
+			recent:   list.New(),
+			created:  expiryQueue{byCreation: true},
+		}
+		if s.maxSessions > 0 {
+			s.shards[i].maxSessions = s.maxSessions / len(s.shards)
+		}
+		if s.maxBytes > 0 {
+			s.shards[i].maxBytes = s.maxBytes / len(s.shards)
+		}
+	}
+	return s
+}
+
+// shard returns the shard holding the session stored under id.
+func (s *inMemorySessionStore) shard(id string) *storeShard {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+func (s *inMemorySessionStore) read(id string) *Session {
+	shard := s.shard(id)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	if elem, ok := shard.sessions[id]; ok {
+		return elem.Value.(*storeEntry).session
+	}
+	return nil
+}
+
+func (s *inMemorySessionStore) write(session *Session) error {
+	size := 0
+	if s.maxBytes > 0 {
+		// Sessions that cannot be encoded are not held against the budget.
+		size, _ = session.size()
+	}
+
+	shard := s.shard(session.storeKey())
+	shard.mu.Lock()
+	if elem, ok := shard.sessions[session.storeKey()]; ok {
+		entry := elem.Value.(*storeEntry)
+		if entry.session != session && entry.session.Version() != session.Version() {
+			shard.mu.Unlock()
+			return ErrVersionConflict
+		}
+		shard.bytes += size - entry.size
+		entry.session = session
+		entry.size = size
+		entry.activity = session.getLastActivity()
+		heap.Fix(&shard.idle, entry.idleIndex)
+		heap.Fix(&shard.created, entry.createdIndex)
+		shard.recent.MoveToFront(elem)
+	} else {
+		entry := &storeEntry{session: session, size: size, activity: session.getLastActivity()}
+		heap.Push(&shard.idle, entry)
+		heap.Push(&shard.created, entry)
+		shard.sessions[session.storeKey()] = shard.recent.PushFront(entry)
+		shard.bytes += size
+	}
+	session.saved()
+
+	var evicted []*Session
+	for len(shard.sessions) > 1 && shard.full() {
+		back := shard.recent.Back()
+		shard.remove(back)
+		evicted = append(evicted, back.Value.(*storeEntry).session)
+	}
+	shard.mu.Unlock()
+
+	if s.onEvict != nil {
+		for _, session := range evicted {
+			s.onEvict(session)
+		}
+	}
+	return nil
+}
+
+// full reports whether the shard exceeds its caps. The shard has to be locked.
+func (s *storeShard) full() bool {
+	return (s.maxSessions > 0 && len(s.sessions) > s.maxSessions) || (s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+func (s *inMemorySessionStore) destroy(id string) error {
+	shard := s.shard(id)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if elem, ok := shard.sessions[id]; ok {
+		shard.remove(elem)
+	}
+	return nil
+}
+
+// remove deletes the session of elem. The shard has to be locked.
+func (s *storeShard) remove(elem *list.Element) {
+	entry := elem.Value.(*storeEntry)
+	delete(s.sessions, entry.session.storeKey())
+	s.recent.Remove(elem)
+	heap.Remove(&s.idle, entry.idleIndex)
+	heap.Remove(&s.created, entry.createdIndex)
+	s.bytes -= entry.size
+}
+
+func (s *inMemorySessionStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	s.gcMaxDuration = maxDuration
+	s.gcMaxDeletes = maxDeletes
+}
+
+func (s *inMemorySessionStore) setClock(clock Clock) {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	s.clock = clock
+}
+
+// now must be called with gcMu held.
+func (s *inMemorySessionStore) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// gc locks one shard at a time and only visits the expired sessions, taken from the fronts of the
+// expiry queues. expired is called after the shard was unlocked.
+func (s *inMemorySessionStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	budget := newGCBudget(s.gcMaxDuration, s.gcMaxDeletes)
+	for range s.shards {
+		shard := s.shards[s.gcCursor]
+		shard.mu.Lock()
+		removed := shard.collect(s.now(), idleExpiration, absoluteExpiration, budget)
+		shard.mu.Unlock()
+
+		for _, session := range removed {
+			expired(session)
+		}
+		if budget.exhausted() {
+			// The shard may hold more expired sessions, start the next collection with it.
+			break
+		}
+		s.gcCursor = (s.gcCursor + 1) % len(s.shards)
+	}
+	return nil
+}
+
+// collect removes the expired sessions of the shard, which has to be locked, until the budget is exhausted.
+func (s *storeShard) collect(now time.Time, idleExpiration, absoluteExpiration time.Duration, budget *gcBudget) []*Session {
+	var removed []*Session
+	for !budget.exhausted() && s.created.Len() > 0 &&
+		now.Sub(s.created.entries[0].session.createdAt) > absoluteExpiration {
+		entry := s.created.entries[0]
+		s.remove(s.sessions[entry.session.storeKey()])
+		removed = append(removed, entry.session)
+		budget.deletes++
+	}
+	for !budget.exhausted() && s.idle.Len() > 0 && now.Sub(s.idle.entries[0].activity) > idleExpiration {
+		entry := s.idle.entries[0]
+		if activity := entry.session.getLastActivity(); now.Sub(activity) <= idleExpiration {
+			entry.activity = activity
+			heap.Fix(&s.idle, entry.idleIndex)
+			continue
+		}
+		s.remove(s.sessions[entry.session.storeKey()])
+		removed = append(removed, entry.session)
+		budget.deletes++
+	}
+	return removed
+}
+
+// count returns the number of stored sessions.
+func (s *inMemorySessionStore) count() int {
+	n := 0
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		n += len(shard.sessions)
+		shard.mu.RUnlock()
+	}
+	return n
+}
+
+// expiryQueue is a min-heap of store entries ordered by their last activity, or by their creation if byCreation is set.
+type expiryQueue struct {
+	entries    []*storeEntry
+	byCreation bool
+}
+
+func (q *expiryQueue) Len() int {
+	return len(q.entries)
+}
+
+func (q *expiryQueue) Less(i, j int) bool {
+	if q.byCreation {
+		return q.entries[i].session.createdAt.Before(q.entries[j].session.createdAt)
+	}
+	return q.entries[i].activity.Before(q.entries[j].activity)
+}
+
+func (q *expiryQueue) Swap(i, j int) {
+	q.entries[i], q.entries[j] = q.entries[j], q.entries[i]
+	*q.index(q.entries[i]) = i
+	*q.index(q.entries[j]) = j
+}
+
+func (q *expiryQueue) Push(x any) {
+	entry := x.(*storeEntry)
+	*q.index(entry) = len(q.entries)
+	q.entries = append(q.entries, entry)
+}
+
+func (q *expiryQueue) Pop() any {
+	entry := q.entries[len(q.entries)-1]
+	q.entries[len(q.entries)-1] = nil
+	q.entries = q.entries[:len(q.entries)-1]
+	return entry
+}
+
+// index returns the field holding the position of entry in the queue.
+func (q *expiryQueue) index(entry *storeEntry) *int {
+	if q.byCreation {
+		return &entry.createdIndex
+	}
+	return &entry.idleIndex
+}
@@ -0,0 +1,166 @@
+package session
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedStore(t *testing.T) {
+	assert.Panics(t, func() { NewInMemorySessionStore(WithShards(0)) })
+
+	for _, shards := range []int{1, 8} {
+		store := NewInMemorySessionStore(WithShards(shards))
+		assert.Len(t, store.shards, shards)
+
+		var wg sync.WaitGroup
+		sessions := make([]*Session, 100)
+		for i := range sessions {
+			sess, err := newSession(generateSessionID)
+			assert.NoError(t, err)
+			sessions[i] = sess
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, store.write(sess))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 100, store.count())
+		for _, sess := range sessions {
+			assert.Same(t, sess, store.read(sess.id))
+		}
+
+		assert.NoError(t, store.destroy(sessions[0].id))
+		assert.Nil(t, store.read(sessions[0].id))
+
+		var expired int
+		assert.NoError(t, store.gc(time.Hour, 0, func(*Session) { expired++ }))
+		assert.Equal(t, 99, expired)
+		assert.Zero(t, store.count())
+	}
+}
+
+func TestMaxSessions(t *testing.T) {
+	store := NewInMemorySessionStore(WithShards(1), WithMaxSessions(2))
+	sessions := make([]*Session, 3)
+	for i := range sessions {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		sessions[i] = sess
+	}
+
+	assert.NoError(t, store.write(sessions[0]))
+	assert.NoError(t, store.write(sessions[1]))
+	// Writing the first session again makes the second one the least recent.
+	assert.NoError(t, store.write(sessions[0]))
+	assert.NoError(t, store.write(sessions[2]))
+
+	assert.Equal(t, 2, store.count())
+	assert.NotNil(t, store.read(sessions[0].id))
+	assert.Nil(t, store.read(sessions[1].id))
+	assert.NotNil(t, store.read(sessions[2].id))
+
+	sharded := NewInMemorySessionStore(WithMaxSessions(64), WithShards(4))
+	for range 200 {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		assert.NoError(t, sharded.write(sess))
+	}
+	assert.LessOrEqual(t, sharded.count(), 64)
+
+	// A cap below the default 32 shards is not rounded up to a session per shard.
+	small := NewInMemorySessionStore(WithMaxSessions(10))
+	for range 200 {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		assert.NoError(t, small.write(sess))
+	}
+	assert.LessOrEqual(t, small.count(), 10)
+	assert.Len(t, small.shards, 10)
+}
+
+func TestMaxMemoryBytes(t *testing.T) {
+	var evicted []*Session
+	store := NewInMemorySessionStore(
+		WithShards(1),
+		WithMaxMemoryBytes(100),
+		WithEvictionHandler(func(session *Session) { evicted = append(evicted, session) }),
+	)
+	sessions := make([]*Session, 3)
+	for i := range sessions {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		sess.Put("data", strings.Repeat("x", 30))
+		sessions[i] = sess
+		assert.NoError(t, store.write(sess))
+	}
+	// Each session encodes to 41 bytes, so only two fit.
+	assert.Equal(t, []*Session{sessions[0]}, evicted)
+	assert.Equal(t, 82, store.shards[0].bytes)
+
+	sessions[1].Put("data", strings.Repeat("x", 80))
+	assert.NoError(t, store.write(sessions[1]))
+	assert.Equal(t, []*Session{sessions[0], sessions[2]}, evicted)
+	assert.NotNil(t, store.read(sessions[1].id))
+
+	assert.NoError(t, store.destroy(sessions[1].id))
+	assert.Zero(t, store.shards[0].bytes)
+}
+
+func TestExpiryQueues(t *testing.T) {
+	store := NewInMemorySessionStore(WithShards(1))
+	now := time.Now()
+	sessions := make([]*Session, 4)
+	for i := range sessions {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		sess.setLastActivity(now.Add(-time.Duration(i) * time.Minute))
+		sessions[i] = sess
+		assert.NoError(t, store.write(sess))
+	}
+	// Idle for 3 minutes when written, but read since.
+	sessions[3].Get("key")
+	// Created long ago but active.
+	sessions[0].createdAt = now.Add(-time.Hour)
+	assert.NoError(t, store.write(sessions[0]))
+
+	var expired []*Session
+	assert.NoError(t, store.gc(90*time.Second, 30*time.Minute, func(session *Session) {
+		expired = append(expired, session)
+	}))
+	assert.ElementsMatch(t, []*Session{sessions[0], sessions[2]}, expired)
+	assert.Equal(t, 2, store.count())
+	shard := store.shards[0]
+	assert.Equal(t, 2, shard.idle.Len())
+	assert.Same(t, sessions[1], shard.idle.entries[0].session)
+	for i, entry := range shard.created.entries {
+		assert.Equal(t, i, entry.createdIndex)
+	}
+}
+
+func TestGCBudget(t *testing.T) {
+	store := NewInMemorySessionStore(WithShards(4))
+	sm := NewSessionManager(WithStore(store), WithGCBudget(time.Minute, 3))
+	t.Cleanup(func() { sm.Close() })
+	for range 10 {
+		sess, err := newSession(generateSessionID)
+		assert.NoError(t, err)
+		sess.setLastActivity(time.Now().Add(-time.Hour))
+		assert.NoError(t, store.write(sess))
+	}
+
+	var expired int
+	count := func(*Session) { expired++ }
+	assert.NoError(t, store.gc(time.Minute, time.Hour, count))
+	assert.Equal(t, 3, expired)
+	assert.Equal(t, 7, store.count())
+	for range 3 {
+		assert.NoError(t, store.gc(time.Minute, time.Hour, count))
+	}
+	assert.Equal(t, 10, expired)
+	assert.Zero(t, store.count())
+}
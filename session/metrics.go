@@ -0,0 +1,266 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics counts session lifecycle events and measures store operations. It serves them in the
+// Prometheus text format, so Prometheus can scrape it without the client library:
+//
+//	metrics := session.NewMetrics()
+//	sm := session.NewSessionManager(session.WithMetrics(metrics))
+//	r.GET("/metrics", gin.WrapH(metrics))
+type Metrics struct {
+	created   atomic.Uint64
+	destroyed atomic.Uint64
+	expired   atomic.Uint64
+	corrupted atomic.Uint64
+
+	storeDuration map[string]*histogram
+	storeErrors   map[string]*atomic.Uint64
+	gcDuration    histogram
+	// store is counted for session_active, see storeCount.
+	store atomic.Pointer[SessionStore]
+}
+
+// storeOps are the store operations measured by Metrics. Collections are measured by gcDuration instead.
+var storeOps = []string{"read", "write", "destroy", "gc"}
+
+// latencyBuckets are the upper bounds in seconds of the histogram buckets.
+var latencyBuckets = [...]float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		storeDuration: make(map[string]*histogram, len(storeOps)),
+		storeErrors:   make(map[string]*atomic.Uint64, len(storeOps)),
+	}
+	for _, op := range storeOps {
+		m.storeDuration[op] = &histogram{}
+		m.storeErrors[op] = &atomic.Uint64{}
+	}
+	return m
+}
+
+// WithMetrics records the sessions created, destroyed and expired, the latency and errors of the
+// store operations and the duration of garbage collections in metrics.
+func WithMetrics(metrics *Metrics) Option {
+	return func(s *SessionManager) {
+		s.metrics = metrics
+	}
+}
+
+// useMetrics counts the lifecycle hooks and wraps the store for WithMetrics.
+func (m *SessionManager) useMetrics() {
+	if m.metrics == nil {
+		return
+	}
+	metrics := m.metrics
+	hooks := m.hooks
+	m.hooks.OnCreate = func(session *Session) {
+		metrics.created.Add(1)
+		hooks.create(session)
+	}
+	m.hooks.OnDestroy = func(session *Session) {
+		metrics.destroyed.Add(1)
+		hooks.destroy(session)
+	}
+	m.hooks.OnExpire = func(session *Session) {
+		metrics.expired.Add(1)
+		hooks.expire(session)
+	}
+	m.store = &metricsStore{inner: m.store, metrics: metrics}
+	metrics.store.Store(&m.store)
+}
+
+// active returns the number of stored sessions, or the sessions created and neither destroyed nor
+// expired since the start if the store cannot count.
+func (m *Metrics) active() int64 {
+	if store := m.store.Load(); store != nil {
+		if n := storeCount(*store); n >= 0 {
+			return int64(n)
+		}
+	}
+	return int64(m.created.Load()) - int64(m.destroyed.Load()) - int64(m.expired.Load())
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	created, destroyed, expired := m.created.Load(), m.destroyed.Load(), m.expired.Load()
+
+	fmt.Fprintln(cw, "# HELP session_active Sessions in the store, or created and neither destroyed nor expired since the start if the store cannot count them.")
+	fmt.Fprintln(cw, "# TYPE session_active gauge")
+	fmt.Fprintf(cw, "session_active %d\n", m.active())
+	for _, counter := range []struct {
+		name, help string
+		value      uint64
+	}{
+		{"session_created_total", "Sessions saved for the first time.", created},
+		{"session_destroyed_total", "Sessions destroyed.", destroyed},
+		{"session_expired_total", "Sessions removed because of their expiration.", expired},
+		{"session_corrupted_total", "Stored sessions destroyed because their data could not be decoded.", m.corrupted.Load()},
+	} {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", counter.name, counter.help, counter.name, counter.name, counter.value)
+	}
+
+	fmt.Fprintln(cw, "# HELP session_store_duration_seconds Latency of store operations.")
+	fmt.Fprintln(cw, "# TYPE session_store_duration_seconds histogram")
+	for _, op := range storeOps[:3] {
+		m.storeDuration[op].write(cw, "session_store_duration_seconds", `op="`+op+`"`)
+	}
+	fmt.Fprintln(cw, "# HELP session_store_errors_total Failed store operations.")
+	fmt.Fprintln(cw, "# TYPE session_store_errors_total counter")
+	for _, op := range storeOps {
+		fmt.Fprintf(cw, "session_store_errors_total{op=%q} %d\n", op, m.storeErrors[op].Load())
+	}
+	fmt.Fprintln(cw, "# HELP session_gc_duration_seconds Duration of garbage collections, including the expiration hooks.")
+	fmt.Fprintln(cw, "# TYPE session_gc_duration_seconds histogram")
+	m.gcDuration.write(cw, "session_gc_duration_seconds", "")
+	return cw.n, cw.err
+}
+
+// observe records the latency and outcome of a store operation started at start.
+func (m *Metrics) observe(op string, start time.Time, err error) {
+	m.storeDuration[op].observe(time.Since(start))
+	if err != nil {
+		m.storeErrors[op].Add(1)
+	}
+}
+
+// histogram counts observations in latencyBuckets.
+type histogram struct {
+	buckets [len(latencyBuckets) + 1]atomic.Uint64 // the last one is +Inf
+	count   atomic.Uint64
+	sumBits atomic.Uint64 // float64 seconds
+}
+
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && seconds > latencyBuckets[i] {
+		i++
+	}
+	h.buckets[i].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sumBits.Load()
+		if h.sumBits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+seconds)) {
+			return
+		}
+	}
+}
+
+func (h *histogram) write(w io.Writer, name, labels string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	var cumulative uint64
+	for i := range h.buckets {
+		cumulative += h.buckets[i].Load()
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = strconv.FormatFloat(latencyBuckets[i], 'g', -1, 64)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=%q} %d\n", name, labels, sep, le, cumulative)
+	}
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, math.Float64frombits(h.sumBits.Load()))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count.Load())
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
+
+// metricsStore measures the operations of the inner store.
+type metricsStore struct {
+	inner   SessionStore
+	metrics *Metrics
+}
+
+func (s *metricsStore) read(id string) *Session {
+	defer s.metrics.observe("read", time.Now(), nil)
+	return s.inner.read(id)
+}
+
+func (s *metricsStore) readChecked(id string) (*Session, error) {
+	start := time.Now()
+	session, err := readStore(s.inner, id)
+	s.metrics.observe("read", start, err)
+	return session, err
+}
+
+func (s *metricsStore) write(session *Session) error {
+	start := time.Now()
+	err := writeStore(s.inner, session)
+	s.metrics.observe("write", start, err)
+	return err
+}
+
+func (s *metricsStore) destroy(id string) error {
+	start := time.Now()
+	err := s.inner.destroy(id)
+	s.metrics.observe("destroy", start, err)
+	return err
+}
+
+func (s *metricsStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	start := time.Now()
+	err := s.inner.gc(idleExpiration, absoluteExpiration, expired)
+	s.metrics.gcDuration.observe(time.Since(start))
+	if err != nil {
+		s.metrics.storeErrors["gc"].Add(1)
+	}
+	return err
+}
+
+func (s *metricsStore) sharesSessions() bool {
+	return sharesSessions(s.inner)
+}
+
+func (s *metricsStore) invalidate(key string) {
+	invalidate(s.inner, key)
+}
+
+func (s *metricsStore) count() int {
+	return storeCount(s.inner)
+}
+
+func (s *metricsStore) unwrap() SessionStore {
+	return s.inner
+}
+
+// Close closes the inner store if it is an io.Closer.
+func (s *metricsStore) Close() error {
+	if closer, ok := s.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	var created int
+	sm := NewSessionManager(
+		WithMetrics(metrics),
+		WithAbsoluteExpiration(time.Hour),
+		WithHooks(Hooks{OnCreate: func(*Session) { created++ }}),
+	)
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+	router.GET("/destroy", func(c *gin.Context) {
+		assert.NoError(t, sm.Destroy(c))
+	})
+	router.GET("/metrics", gin.WrapH(metrics))
+
+	do := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+	cookie := do("/", nil).Result().Cookies()[0]
+	do("/", nil)
+	do("/", cookie)
+	do("/destroy", cookie)
+	_, err := sm.RunGC(context.Background())
+	assert.NoError(t, err)
+
+	body := do("/metrics", nil).Body.String()
+	assert.Equal(t, 2, created)
+	assert.Contains(t, body, "session_active 1\n")
+	assert.Contains(t, body, "session_created_total 2\n")
+	assert.Contains(t, body, "session_destroyed_total 1\n")
+	assert.Contains(t, body, `session_store_duration_seconds_bucket{op="write",le="+Inf"} 3`)
+	assert.Contains(t, body, `session_store_errors_total{op="write"} 0`)
+	assert.Contains(t, body, "session_gc_duration_seconds_count 1\n")
+}
+
+func TestMetricsActiveCountsStore(t *testing.T) {
+	store := NewInMemorySessionStore()
+	existing, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	assert.NoError(t, store.write(existing))
+	metrics := NewMetrics()
+	sm := NewSessionManager(WithStore(store), WithMetrics(metrics))
+	t.Cleanup(func() { sm.Close() })
+
+	rw := httptest.NewRecorder()
+	metrics.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	// the session was stored before the manager started, the counters don't know it
+	assert.Contains(t, rw.Body.String(), "session_active 1\n")
+	assert.Contains(t, rw.Body.String(), "session_created_total 0\n")
+}
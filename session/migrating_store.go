@@ -0,0 +1,135 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// migratingStore moves sessions from one store to another without logging users out.
+type migratingStore struct {
+	old, new SessionStore
+	oldReads atomic.Uint64
+}
+
+// NewMigratingStore returns a store for moving from old to new, e.g. from the in-memory store to a
+// shared one: sessions are read from new and from old if new misses them, written to and destroyed
+// in both, so old stays complete until the migration is finished and can be switched back to.
+// Sessions move as they are saved; once Progress reports no more reads from old for a while, or after
+// the absolute expiration, old can be removed.
+func NewMigratingStore(old, new SessionStore) *migratingStore {
+	return &migratingStore{old: old, new: new}
+}
+
+// MigrationProgress reports the state of a migration of NewMigratingStore.
+type MigrationProgress struct {
+	// OldSessions and NewSessions are the number of sessions in the stores, -1 if a store cannot count them.
+	OldSessions int
+	NewSessions int
+	// OldReads counts the sessions read from old because new did not have them yet.
+	OldReads uint64
+}
+
+// Progress returns how far the migration is.
+func (s *migratingStore) Progress() MigrationProgress {
+	return MigrationProgress{
+		OldSessions: storeCount(s.old),
+		NewSessions: storeCount(s.new),
+		OldReads:    s.oldReads.Load(),
+	}
+}
+
+func (s *migratingStore) read(id string) *Session {
+	if session := s.new.read(id); session != nil {
+		return session
+	}
+	session := s.old.read(id)
+	if session != nil {
+		s.oldReads.Add(1)
+	}
+	return session
+}
+
+func (s *migratingStore) readChecked(id string) (*Session, error) {
+	session, err := readStore(s.new, id)
+	if session != nil || err != nil {
+		return session, err
+	}
+	session, err = readStore(s.old, id)
+	if session != nil {
+		s.oldReads.Add(1)
+	}
+	return session, err
+}
+
+// write stores copies in both stores, as each of them updates the version of the session it writes.
+func (s *migratingStore) write(session *Session) error {
+	values := session.values()
+	if err := s.new.write(session.withData(values)); err != nil {
+		return err
+	}
+	if err := s.old.write(session.withData(values)); err != nil {
+		return err
+	}
+	session.saved()
+	return nil
+}
+
+func (s *migratingStore) destroy(id string) error {
+	return errors.Join(s.new.destroy(id), s.old.destroy(id))
+}
+
+// gc collects both stores, reporting sessions expired in both once.
+func (s *migratingStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	reported := make(map[string]bool)
+	err := s.new.gc(idleExpiration, absoluteExpiration, func(session *Session) {
+		reported[session.storeKey()] = true
+		expired(session)
+	})
+	return errors.Join(err, s.old.gc(idleExpiration, absoluteExpiration, func(session *Session) {
+		if !reported[session.storeKey()] {
+			expired(session)
+		}
+	}))
+}
+
+func (s *migratingStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	for _, inner := range []SessionStore{s.new, s.old} {
+		if store, ok := inner.(gcBudgeter); ok {
+			store.setGCBudget(maxDuration, maxDeletes)
+		}
+	}
+}
+
+func (s *migratingStore) setClock(clock Clock) {
+	for _, inner := range []SessionStore{s.new, s.old} {
+		if store, ok := inner.(clockSetter); ok {
+			store.setClock(clock)
+		}
+	}
+}
+
+func (s *migratingStore) setLogger(logger Logger) {
+	for _, inner := range []SessionStore{s.new, s.old} {
+		if store, ok := inner.(loggerSetter); ok {
+			store.setLogger(logger)
+		}
+	}
+}
+
+func (s *migratingStore) invalidate(key string) {
+	invalidate(s.new, key)
+	invalidate(s.old, key)
+}
+
+// Close closes both stores if they are io.Closers.
+func (s *migratingStore) Close() error {
+	var err error
+	for _, inner := range []SessionStore{s.new, s.old} {
+		if closer, ok := inner.(io.Closer); ok {
+			err = errors.Join(err, closer.Close())
+		}
+	}
+	return err
+}
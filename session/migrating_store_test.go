@@ -0,0 +1,72 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigratingStore(t *testing.T) {
+	old, new := NewInMemorySessionStore(), NewInMemorySessionStore()
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("count", 1)
+	assert.NoError(t, old.write(sess))
+
+	store := NewMigratingStore(old, new)
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		s := GetSession(c)
+		count, _ := GetGenericValue[int](s, "count")
+		s.Put("count", count+1)
+	})
+	cookie := &http.Cookie{Name: "session", Value: sess.id}
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookie)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 3, new.read(sess.id).Get("count"))
+	assert.Equal(t, 3, old.read(sess.id).Get("count"))
+	assert.Equal(t, MigrationProgress{OldSessions: 1, NewSessions: 1, OldReads: 1}, store.Progress())
+
+	assert.NoError(t, store.destroy(sess.id))
+	assert.Nil(t, old.read(sess.id))
+	assert.Nil(t, new.read(sess.id))
+}
+
+func TestMigratingStoreGC(t *testing.T) {
+	old, new := NewInMemorySessionStore(), NewInMemorySessionStore()
+	var expired int
+	sm := NewSessionManager(
+		WithStore(NewMigratingStore(old, new)),
+		WithAbsoluteExpiration(time.Hour),
+		WithHooks(Hooks{OnExpire: func(*Session) { expired++ }}),
+	)
+	t.Cleanup(func() { sm.Close() })
+	migrated, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	migrated.Put("key", "value")
+	migrated.createdAt = time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, sm.save(migrated, nil))
+	unmigrated, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	unmigrated.createdAt = time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, old.write(unmigrated))
+
+	stats, err := sm.RunGC(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Deleted)
+	assert.Equal(t, 2, expired)
+	assert.Zero(t, old.count())
+	assert.Zero(t, new.count())
+}
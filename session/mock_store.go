@@ -0,0 +1,128 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// MockResults scripts the outcomes of the next calls of each store operation, see NewMockStore.
+// A nil error lets the call through to memory. A scripted read error is passed to the error handler
+// of WithErrorHandler, except ErrUnknownSession which makes the session appear missing.
+type MockResults struct {
+	Read    []error
+	Write   []error
+	Destroy []error
+	GC      []error
+}
+
+// MockCalls counts the calls of each store operation.
+type MockCalls struct {
+	Read    int
+	Write   int
+	Destroy int
+	GC      int
+}
+
+// NewMockStore returns an in-memory store whose calls fail as scripted with Script, to unit test
+// how an application handles store errors without a real backend.
+func NewMockStore() *mockStore {
+	return &mockStore{inner: NewInMemorySessionStore()}
+}
+
+type mockStore struct {
+	inner *inMemorySessionStore
+
+	mu     sync.Mutex
+	script MockResults
+	calls  MockCalls
+}
+
+// Script appends results to the outcomes of the next calls. Calls beyond the script succeed.
+func (s *mockStore) Script(results MockResults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.script.Read = append(s.script.Read, results.Read...)
+	s.script.Write = append(s.script.Write, results.Write...)
+	s.script.Destroy = append(s.script.Destroy, results.Destroy...)
+	s.script.GC = append(s.script.GC, results.GC...)
+}
+
+// Calls returns the number of calls of each operation since the store was created.
+func (s *mockStore) Calls() MockCalls {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// next counts a call and takes its scripted result from results.
+func (s *mockStore) next(results *[]error, calls *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	*calls++
+	if len(*results) == 0 {
+		return nil
+	}
+	err := (*results)[0]
+	*results = (*results)[1:]
+	return err
+}
+
+func (s *mockStore) read(id string) *Session {
+	session, _ := s.readChecked(id)
+	return session
+}
+
+func (s *mockStore) readChecked(id string) (*Session, error) {
+	err := s.next(&s.script.Read, &s.calls.Read)
+	if errors.Is(err, ErrUnknownSession) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.inner.read(id), nil
+}
+
+func (s *mockStore) write(session *Session) error {
+	if err := s.next(&s.script.Write, &s.calls.Write); err != nil {
+		return err
+	}
+	return s.inner.write(session)
+}
+
+func (s *mockStore) destroy(id string) error {
+	if err := s.next(&s.script.Destroy, &s.calls.Destroy); err != nil {
+		return err
+	}
+	return s.inner.destroy(id)
+}
+
+func (s *mockStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	if err := s.next(&s.script.GC, &s.calls.GC); err != nil {
+		return err
+	}
+	return s.inner.gc(idleExpiration, absoluteExpiration, expired)
+}
+
+func (s *mockStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	s.inner.setGCBudget(maxDuration, maxDeletes)
+}
+
+func (s *mockStore) setClock(clock Clock) {
+	s.inner.setClock(clock)
+}
+
+func (s *mockStore) invalidate(key string) {
+	invalidate(s.inner, key)
+}
+
+func (s *mockStore) each(fn func(*Session) error) error {
+	return s.inner.each(fn)
+}
+
+func (s *mockStore) count() int {
+	return s.inner.count()
+}
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockStore(t *testing.T) {
+	store := NewMockStore()
+	unavailable := errors.New("store unavailable")
+	var handled []error
+	sm := NewSessionManager(WithStore(store), WithConflictRetries(0), WithErrorHandler(func(c *gin.Context, err error) {
+		handled = append(handled, err)
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("visited", true)
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookie := rw.Result().Cookies()[0]
+
+	store.Script(MockResults{Read: []error{ErrUnknownSession, unavailable}, Write: []error{ErrVersionConflict}})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.NotEqual(t, cookie.Value, rw.Result().Cookies()[0].Value, "missing session is replaced")
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Len(t, handled, 2)
+	assert.ErrorIs(t, handled[0], ErrVersionConflict)
+	assert.ErrorIs(t, handled[1], unavailable)
+
+	assert.Equal(t, MockCalls{Read: 2, Write: 2}, store.Calls())
+	assert.NotNil(t, store.read(cookie.Value))
+}
@@ -0,0 +1,69 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// oauthKeyPrefix prefixes the session keys of pending OAuth flows, followed by the state.
+const oauthKeyPrefix = "oauth."
+
+// OAuthFlow holds the values of an OAuth2 or OpenID Connect authorization request.
+type OAuthFlow struct {
+	// State is sent with the authorization request and checked against the callback.
+	State string
+	// Nonce is sent with OpenID Connect requests and has to match the ID token.
+	Nonce string
+	// Verifier is the PKCE code verifier sent with the token request.
+	Verifier string
+}
+
+// Challenge returns the S256 PKCE code challenge of the verifier.
+func (f OAuthFlow) Challenge() string {
+	sum := sha256.Sum256([]byte(f.Verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// BeginOAuth creates the state, nonce and PKCE verifier of an authorization request that is valid for ttl.
+// Several flows can be pending at once, e.g. from different tabs.
+func (s *Session) BeginOAuth(ttl time.Duration) (OAuthFlow, error) {
+	var flow OAuthFlow
+	for _, v := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		value, err := randomID(32)
+		if err != nil {
+			return OAuthFlow{}, err
+		}
+		*v = value
+	}
+	expires := strconv.FormatInt(s.now().Add(ttl).UnixNano(), 10)
+	s.Put(oauthKeyPrefix+flow.State, flow.Nonce+"."+flow.Verifier+"."+expires)
+	return flow, nil
+}
+
+// CompleteOAuth returns the flow started by BeginOAuth for the state of a callback and removes it,
+// so a state is accepted at most once. It reports false for unknown or expired states.
+func (s *Session) CompleteOAuth(state string) (OAuthFlow, bool) {
+	key := oauthKeyPrefix + state
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.snapshot()[key].(string)
+	if !ok || state == "" {
+		return OAuthFlow{}, false
+	}
+	s.deleteLocked(key)
+
+	parts := strings.Split(stored, ".")
+	if len(parts) != 3 {
+		return OAuthFlow{}, false
+	}
+	nanos, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || s.now().UnixNano() > nanos {
+		return OAuthFlow{}, false
+	}
+	return OAuthFlow{State: state, Nonce: parts[0], Verifier: parts[1]}, true
+}
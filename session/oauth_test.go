@@ -0,0 +1,43 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuthFlow(t *testing.T) {
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+
+	flow, err := sess.BeginOAuth(time.Minute)
+	assert.NoError(t, err)
+	other, err := sess.BeginOAuth(time.Minute)
+	assert.NoError(t, err)
+	assert.NotEqual(t, flow.State, other.State)
+	assert.GreaterOrEqual(t, len(flow.Verifier), 43)
+
+	sum := sha256.Sum256([]byte(flow.Verifier))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(sum[:]), flow.Challenge())
+
+	_, ok := sess.CompleteOAuth("forged")
+	assert.False(t, ok)
+	completed, ok := sess.CompleteOAuth(flow.State)
+	assert.True(t, ok)
+	assert.Equal(t, flow, completed)
+	_, ok = sess.CompleteOAuth(flow.State)
+	assert.False(t, ok)
+
+	completed, ok = sess.CompleteOAuth(other.State)
+	assert.True(t, ok)
+	assert.Equal(t, other, completed)
+
+	expired, err := sess.BeginOAuth(-time.Second)
+	assert.NoError(t, err)
+	_, ok = sess.CompleteOAuth(expired.State)
+	assert.False(t, ok)
+	assert.Empty(t, sess.Keys())
+}
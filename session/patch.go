@@ -0,0 +1,22 @@
+package session
+
+// patcher is implemented by stores which can update the record of a session with the keys changed
+// since it was loaded, instead of serializing all of its data again. Stores keeping a record per key,
+// e.g. a hash or a JSON document patched in place, save the bandwidth of rewriting large sessions
+// for small changes.
+type patcher interface {
+	// patch writes the metadata of session, puts the values of puts and removes the keys of deletes.
+	// Sessions without a stored record are written in full.
+	patch(session *Session, puts map[string]any, deletes []string) error
+}
+
+// writeStore writes session to store, only its changed keys if the store is a patcher and the session
+// was loaded from the store. Wrappers passing sessions through unchanged write to their inner store with it.
+func writeStore(store SessionStore, session *Session) error {
+	p, ok := store.(patcher)
+	if !ok || session.Version() == 0 {
+		return store.write(session)
+	}
+	puts, deletes := session.changes()
+	return p.patch(session, puts, deletes)
+}
@@ -0,0 +1,36 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStorePatch(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "sessions.json"))
+	sm := NewSessionManager(WithStore(store))
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("a", "initial")
+	sess.Put("b", "initial")
+	sess.Put("c", "initial")
+	assert.NoError(t, sm.save(sess, nil))
+
+	loaded := store.read(sess.id)
+	loaded.Put("a", "changed")
+	loaded.Delete("c")
+	// Values that were not changed are not written, so losing them in memory goes unnoticed
+	loaded.setData(map[string]any{"a": "changed"})
+	assert.NoError(t, sm.save(loaded, nil))
+
+	stored := store.read(sess.id)
+	assert.Equal(t, uint64(2), stored.Version())
+	assert.Equal(t, "changed", stored.Get("a"))
+	assert.Equal(t, "initial", stored.Get("b"))
+	assert.Nil(t, stored.Get("c"))
+
+	puts, deletes := loaded.changes()
+	assert.Empty(t, puts)
+	assert.Empty(t, deletes)
+}
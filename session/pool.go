@@ -0,0 +1,103 @@
+package session
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithSessionPooling recycles the Session structs of expired sessions for new ones, which cuts
+// allocations under high request rates. Sessions must not be used after the OnExpire hook returned;
+// builds with the race detector panic on such use. Pooling only takes effect with stores reading
+// copies of the stored sessions, like NewEncryptedStore or WithCompression: the in-memory store and
+// WithSingleflightReads hand the same session to concurrent requests, which may still hold it when it expires.
+func WithSessionPooling(enabled bool) Option {
+	return func(s *SessionManager) {
+		s.pooling = enabled
+	}
+}
+
+// sessionSharer is implemented by stores that tell whether read may return a session other
+// requests hold as well. Stores not implementing it are assumed to share sessions.
+type sessionSharer interface {
+	sharesSessions() bool
+}
+
+func sharesSessions(store SessionStore) bool {
+	if s, ok := store.(sessionSharer); ok {
+		return s.sharesSessions()
+	}
+	return true
+}
+
+// usePooling turns pooling off if a recycled session could still be held by another request.
+func (m *SessionManager) usePooling() {
+	if m.pooling && (m.reads != nil || sharesSessions(m.store)) {
+		m.logger.Debug("session pooling disabled, the store shares sessions between requests")
+		m.pooling = false
+	}
+}
+
+var sessionPool = sync.Pool{
+	New: func() any { return new(Session) },
+}
+
+// acquireSession returns a pooled session initialized as by newSession.
+func acquireSession(id string, clock Clock) *Session {
+	session := sessionPool.Get().(*Session)
+	session.id = id
+	session.clock = clock
+	session.createdAt = session.now()
+	session.fresh = true
+	session.released.Store(false)
+	session.setData(make(map[string]any))
+	session.touch()
+	return session
+}
+
+// releaseSession resets session and puts it back into the pool. Its generation is incremented,
+// so references taken before are detected by checkLive in race builds.
+func releaseSession(session *Session) {
+	session.mu.Lock()
+	session.id = ""
+	session.key = ""
+	session.keys = nil
+	session.changed = nil
+	session.base = nil
+	session.fresh = false
+	session.version = 0
+	session.queued = false
+	session.schema = 0
+	session.cookieIssuedAt = time.Time{}
+	session.ip = ""
+	session.userAgent = ""
+	session.agent = ""
+	session.degraded.Store(false)
+	session.data.Store(nil)
+	session.lazy = nil
+	session.clock = nil
+	session.generation.Add(1)
+	session.released.Store(true)
+	session.mu.Unlock()
+
+	sessionPool.Put(session)
+}
+
+// checkLive panics in race builds if the session was released to the pool,
+// or was released and reused since generation was observed.
+func (s *Session) checkLive(generation uint64) {
+	if !poolChecks {
+		return
+	}
+	if s.released.Load() || s.generation.Load() != generation {
+		panic(fmt.Sprintf("session: use of a session after it was released to the pool (generation %d, now %d)",
+			generation, s.generation.Load()))
+	}
+}
+
+// checkReleased panics in race builds if the session is in the pool.
+func (s *Session) checkReleased() {
+	if poolChecks && s.released.Load() {
+		panic("session: use of a session after it was released to the pool")
+	}
+}
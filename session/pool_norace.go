@@ -0,0 +1,6 @@
+//go:build !race
+
+package session
+
+// poolChecks enables the use-after-release checks of pooled sessions.
+const poolChecks = false
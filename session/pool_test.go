@@ -0,0 +1,76 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionPooling(t *testing.T) {
+	store, err := NewEncryptedStoreFromKeys(NewInMemorySessionStore(), StaticKeys([]byte("0123456789abcdef")))
+	assert.NoError(t, err)
+	var expired *Session
+	sm := NewSessionManager(WithStore(store), WithSessionPooling(true), WithAbsoluteExpiration(time.Minute),
+		WithHooks(Hooks{OnExpire: func(session *Session) { expired = session }}))
+	t.Cleanup(func() { sm.Close() })
+	assert.True(t, sm.pooling)
+
+	sess, err := sm.newSession()
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+	sess.createdAt = time.Now().Add(-time.Hour)
+	assert.NoError(t, sm.save(sess, nil))
+
+	stats, err := sm.RunGC(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Deleted)
+	// The collection expires the copy it decrypted
+	sess = expired
+	assert.True(t, sess.released.Load())
+	assert.Empty(t, sess.id)
+	assert.Nil(t, sess.snapshot())
+
+	if poolChecks {
+		assert.Panics(t, func() { sess.Put("key", "value") })
+	}
+
+	fresh, err := sm.newSession()
+	assert.NoError(t, err)
+	assert.False(t, fresh.released.Load())
+	assert.True(t, fresh.isFresh())
+	assert.NotEmpty(t, fresh.id)
+	assert.Empty(t, fresh.Keys())
+}
+
+func TestSessionPoolingSharedSessions(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	sm := NewSessionManager(WithSessionPooling(true), WithClock(clock))
+	t.Cleanup(func() { sm.Close() })
+	assert.False(t, sm.pooling)
+
+	ctx := context.Background()
+	created, err := sm.Create(ctx)
+	assert.NoError(t, err)
+	created.Put("user", "alice")
+	assert.NoError(t, sm.Commit(ctx, created))
+
+	// A request holds the session while the collection expires it
+	held, err := sm.Load(ctx, created.ID())
+	assert.NoError(t, err)
+	clock.advance(time.Hour)
+	stats, err := sm.RunGC(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Deleted)
+
+	for range 10 {
+		other, err := sm.Create(ctx)
+		assert.NoError(t, err)
+		assert.NotSame(t, held, other)
+		other.Put("user", "bob")
+	}
+	held.Put("theme", "dark")
+	assert.Equal(t, "alice", held.Get("user"))
+	assert.Equal(t, created.ID(), held.ID())
+}
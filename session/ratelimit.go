@@ -0,0 +1,46 @@
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// rateLimitKeyPrefix prefixes the session keys of the counters of Allow, followed by the action.
+const rateLimitKeyPrefix = "ratelimit."
+
+// Allow reports whether action may be performed again, allowing limit actions per window, e.g. login
+// attempts or OTP resends. It uses a sliding window counter: the count of the previous window is weighted
+// by its overlap with the sliding window. Denied calls are not counted.
+func (s *Session) Allow(action string, limit int, window time.Duration) bool {
+	key := rateLimitKeyPrefix + action
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var start int64
+	var previous, current int
+	stored, _ := s.snapshot()[key].(string)
+	_, err := fmt.Sscanf(stored, "%d.%d.%d", &start, &previous, &current)
+	if err != nil {
+		start = now.UnixNano()
+	}
+
+	elapsed := now.Sub(time.Unix(0, start))
+	switch {
+	case elapsed >= 2*window:
+		start, previous, current = now.UnixNano(), 0, 0
+		elapsed = 0
+	case elapsed >= window:
+		start, previous, current = start+window.Nanoseconds(), current, 0
+		elapsed -= window
+	}
+
+	weight := 1 - float64(elapsed)/float64(window)
+	if float64(previous)*weight+float64(current) >= float64(limit) {
+		return false
+	}
+
+	s.putLocked(key, fmt.Sprintf("%d.%d.%d", start, previous, current+1))
+	return true
+}
@@ -0,0 +1,30 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllow(t *testing.T) {
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+
+	for range 3 {
+		assert.True(t, sess.Allow("otp", 3, time.Minute))
+	}
+	assert.False(t, sess.Allow("otp", 3, time.Minute))
+	assert.False(t, sess.isFresh())
+	assert.True(t, sess.Allow("login", 1, time.Minute))
+
+	// Half of the previous window still counts, so 3 * 0.5 + 2 reaches the limit.
+	sess.Put(rateLimitKeyPrefix+"otp", fmt.Sprintf("%d.0.3", time.Now().Add(-90*time.Second).UnixNano()))
+	assert.True(t, sess.Allow("otp", 3, time.Minute))
+	assert.True(t, sess.Allow("otp", 3, time.Minute))
+	assert.False(t, sess.Allow("otp", 3, time.Minute))
+
+	sess.Put(rateLimitKeyPrefix+"otp", fmt.Sprintf("%d.0.3", time.Now().Add(-3*time.Minute).UnixNano()))
+	assert.True(t, sess.Allow("otp", 3, time.Minute))
+}
@@ -0,0 +1,264 @@
+package session
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditRememberTheft is audited when an already rotated remember-me token is presented again,
+// which means it was copied. The token family and all sessions of the user are destroyed.
+const AuditRememberTheft AuditEventType = "remember_token_theft"
+
+// rememberGracePeriod is how long a rotated remember-me token is still accepted, so parallel
+// requests sent before the client got its successor are not taken for a theft.
+const rememberGracePeriod = 30 * time.Second
+
+// RememberToken is the server side record of a remember-me token.
+type RememberToken struct {
+	// Family is shared by all tokens rotated from the same login.
+	Family string
+	// UserID is the id passed to Remember. It is put into the remembered sessions as it is, so
+	// stores encoding tokens have to restore its type.
+	UserID any
+	// ValidatorHash is the SHA-256 of the secret part of the token.
+	ValidatorHash string
+	Expires       time.Time
+	// Rotated is set once the token was exchanged for its successor at RotatedAt.
+	Rotated   bool
+	RotatedAt time.Time
+}
+
+// RememberStore keeps remember-me tokens by selector, the public part of a token.
+type RememberStore interface {
+	SaveRememberToken(selector string, token RememberToken) error
+	RememberToken(selector string) (RememberToken, bool, error)
+	DeleteRememberFamily(family string) error
+}
+
+type inMemoryRememberStore struct {
+	mu     sync.Mutex
+	tokens map[string]RememberToken
+	clock  Clock
+}
+
+func NewInMemoryRememberStore() *inMemoryRememberStore {
+	return &inMemoryRememberStore{
+		tokens: make(map[string]RememberToken),
+	}
+}
+
+func (s *inMemoryRememberStore) SaveRememberToken(selector string, token RememberToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// drop expired tokens on the way
+	for sel, t := range s.tokens {
+		if s.now().After(t.Expires) {
+			delete(s.tokens, sel)
+		}
+	}
+	s.tokens[selector] = token
+	return nil
+}
+
+func (s *inMemoryRememberStore) setClock(clock Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock = clock
+}
+
+// now must be called with mu held.
+func (s *inMemoryRememberStore) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+func (s *inMemoryRememberStore) RememberToken(selector string) (RememberToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[selector]
+	return token, ok, nil
+}
+
+func (s *inMemoryRememberStore) DeleteRememberFamily(family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for selector, token := range s.tokens {
+		if token.Family == family {
+			delete(s.tokens, selector)
+		}
+	}
+	return nil
+}
+
+// WithRememberMe enables remember-me tokens valid for ttl. They are kept in their own cookie,
+// named like the session cookie with a "_remember" suffix, and used to start a new session
+// for the user once the session expired. Every use rotates the token, the previous one is still
+// accepted for 30 seconds for requests sent in parallel.
+func WithRememberMe(ttl time.Duration) Option {
+	return func(s *SessionManager) {
+		s.rememberTTL = ttl
+	}
+}
+
+// WithRememberStore replaces the in-memory store of remember-me tokens.
+func WithRememberStore(store RememberStore) Option {
+	return func(s *SessionManager) {
+		s.rememberStore = store
+	}
+}
+
+// Remember issues a remember-me token for the user, e.g. after Login if "remember me" was checked.
+// It has to be called before the response is written.
+func (m *SessionManager) Remember(c *gin.Context, userID any) error {
+	return m.RememberContext(c.Request.Context(), userID)
+}
+
+// RememberContext is Remember for the request context of Middleware.
+func (m *SessionManager) RememberContext(ctx context.Context, userID any) error {
+	state := stateFrom(ctx)
+	if state == nil {
+		return ErrSessionNotFound
+	}
+	if m.rememberTTL <= 0 {
+		return errors.New("remember-me is not enabled, see WithRememberMe")
+	}
+	family, err := randomID(16)
+	if err != nil {
+		return err
+	}
+	return m.issueRememberToken(state, family, userID)
+}
+
+func (m *SessionManager) issueRememberToken(state *requestState, family string, userID any) error {
+	selector, err := randomID(16)
+	if err != nil {
+		return err
+	}
+	validator, err := randomID(32)
+	if err != nil {
+		return err
+	}
+	expires := m.now().Add(m.rememberTTL)
+	err = m.rememberStore.SaveRememberToken(selector, RememberToken{
+		Family:        family,
+		UserID:        userID,
+		ValidatorHash: hashID(validator),
+		Expires:       expires,
+	})
+	if err != nil {
+		return err
+	}
+	state.setRemember(m.rememberCookie(state.request, selector+"."+validator, int(m.rememberTTL/time.Second)))
+	return nil
+}
+
+// remember starts a session for the user of a valid remember-me token sent with r and rotates the token.
+// It returns nil if there is no valid token.
+func (m *SessionManager) remember(r *http.Request, state *requestState) (*Session, error) {
+	if m.rememberTTL <= 0 {
+		return nil, nil
+	}
+	cookie, err := r.Cookie(m.cookieName + "_remember")
+	if err != nil {
+		return nil, nil
+	}
+	selector, validator, _ := strings.Cut(cookie.Value, ".")
+	token, ok, err := m.rememberStore.RememberToken(selector)
+	if err != nil || !ok || m.now().After(token.Expires) ||
+		subtle.ConstantTimeCompare([]byte(token.ValidatorHash), []byte(hashID(validator))) != 1 {
+		state.setRemember(m.rememberCookie(r, "", -1))
+		return nil, err
+	}
+
+	if token.Rotated && m.now().Sub(token.RotatedAt) <= rememberGracePeriod {
+		// A parallel request sent the token before the client got its successor.
+		return m.rememberedSession(r, token.UserID)
+	}
+	if token.Rotated {
+		m.audit(AuditRememberTheft, nil, r)
+		state.setRemember(m.rememberCookie(r, "", -1))
+		err = m.rememberStore.DeleteRememberFamily(token.Family)
+		if err != nil {
+			return nil, err
+		}
+		return nil, m.DestroyAllForUser(context.Background(), token.UserID)
+	}
+
+	session, err := m.rememberedSession(r, token.UserID)
+	if session == nil {
+		return nil, err
+	}
+	token.Rotated = true
+	token.RotatedAt = m.now()
+	err = m.rememberStore.SaveRememberToken(selector, token)
+	if err != nil {
+		return nil, err
+	}
+	err = m.issueRememberToken(state, token.Family, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// rememberedSession starts a session of the client of r for the remembered user. It returns nil if
+// the user has reached the session limit of WithMaxSessionsPerUser with LimitRejectNew, the token is
+// then kept for a later request.
+func (m *SessionManager) rememberedSession(r *http.Request, userID any) (*Session, error) {
+	err := m.admitUser(r.Context(), userID)
+	if errors.Is(err, ErrTooManySessions) {
+		m.logger.Debug("remembered login rejected", "error", err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	session, err := m.newSession()
+	if err != nil {
+		return nil, err
+	}
+	m.bind(r, session)
+	session.Put(UserIDKey, userID)
+	err = m.userIndex.AddUserSession(userKey(userID), session.storeKey())
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// forget deletes the token family of the remember-me cookie sent with r and expires the cookie.
+func (m *SessionManager) forget(state *requestState) error {
+	if m.rememberTTL <= 0 || state.request == nil {
+		return nil
+	}
+	cookie, err := state.request.Cookie(m.cookieName + "_remember")
+	if err != nil {
+		return nil
+	}
+	state.setRemember(m.rememberCookie(state.request, "", -1))
+	selector, _, _ := strings.Cut(cookie.Value, ".")
+	token, ok, err := m.rememberStore.RememberToken(selector)
+	if err != nil || !ok {
+		return err
+	}
+	return m.rememberStore.DeleteRememberFamily(token.Family)
+}
+
+func (m *SessionManager) rememberCookie(r *http.Request, value string, maxAge int) *http.Cookie {
+	cookie := m.newCookie(r, value, maxAge)
+	cookie.Name = m.cookieName + "_remember"
+	return cookie
+}
@@ -0,0 +1,184 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRememberMe(t *testing.T) {
+	var events []AuditEventType
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(
+		WithStore(store),
+		WithClock(clock),
+		WithRememberMe(30*24*time.Hour),
+		WithAuditSink(AuditFunc(func(event AuditEvent) { events = append(events, event.Type) })),
+	)
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/login", func(c *gin.Context) {
+		assert.NoError(t, sm.Login(c, "alice"))
+		assert.NoError(t, sm.Remember(c, "alice"))
+	})
+	router.GET("/me", func(c *gin.Context) {
+		user, _ := GetSession(c).Get(UserIDKey).(string)
+		c.String(http.StatusOK, user)
+	})
+	router.GET("/logout", func(c *gin.Context) {
+		assert.NoError(t, sm.Logout(c))
+	})
+
+	do := func(path string, cookies ...*http.Cookie) (string, map[string]*http.Cookie) {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		set := map[string]*http.Cookie{}
+		for _, cookie := range rw.Result().Cookies() {
+			set[cookie.Name] = cookie
+		}
+		return rw.Body.String(), set
+	}
+
+	_, cookies := do("/login")
+	remember := cookies["session_remember"]
+	assert.NotNil(t, remember)
+	assert.Greater(t, remember.MaxAge, 0)
+
+	// the session expired, the remember-me token starts a new one and is rotated
+	assert.NoError(t, store.destroy(cookies["session"].Value))
+	user, cookies := do("/me", remember)
+	assert.Equal(t, "alice", user)
+	rotated := cookies["session_remember"]
+	assert.NotEqual(t, remember.Value, rotated.Value)
+	session := cookies["session"]
+	assert.NotNil(t, store.read(session.Value))
+
+	// a parallel request with the old token is still accepted shortly after the rotation
+	clock.advance(rememberGracePeriod)
+	user, cookies = do("/me", remember)
+	assert.Equal(t, "alice", user)
+	assert.NotContains(t, cookies, "session_remember")
+	assert.NotContains(t, events, AuditRememberTheft)
+
+	// replaying the old token later reveals the theft and ends every session of the family and user
+	clock.advance(time.Second)
+	user, cookies = do("/me", remember)
+	assert.Empty(t, user)
+	assert.Less(t, cookies["session_remember"].MaxAge, 0)
+	assert.Nil(t, store.read(session.Value))
+	assert.Contains(t, events, AuditRememberTheft)
+	user, _ = do("/me", rotated)
+	assert.Empty(t, user)
+
+	// logout forgets the token
+	_, cookies = do("/login")
+	remember = cookies["session_remember"]
+	_, cookies = do("/logout", cookies["session"], remember)
+	assert.Less(t, cookies["session_remember"].MaxAge, 0)
+	user, _ = do("/me", remember)
+	assert.Empty(t, user)
+}
+
+func TestRememberMeSession(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store), WithRememberMe(time.Hour), WithBindToUserAgent(true))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/login", func(c *gin.Context) {
+		assert.NoError(t, sm.Login(c, 42))
+		assert.NoError(t, sm.Remember(c, 42))
+	})
+	router.GET("/me", func(c *gin.Context) {
+		c.String(http.StatusOK, "%T %v", GetSession(c).Get(UserIDKey), GetSession(c).Get(UserIDKey))
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/login", nil))
+	cookies := rw.Result().Cookies()
+	i := slices.IndexFunc(cookies, func(cookie *http.Cookie) bool { return cookie.Name == "session_remember" })
+	assert.GreaterOrEqual(t, i, 0)
+	remember := cookies[i]
+
+	rw = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("User-Agent", "browser")
+	req.AddCookie(remember)
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, "int 42", rw.Body.String())
+	sessions, err := sm.userIndex.UserSessions("42")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	cookies = rw.Result().Cookies()
+	i = slices.IndexFunc(cookies, func(cookie *http.Cookie) bool { return cookie.Name == "session" })
+	assert.GreaterOrEqual(t, i, 0)
+	assert.Equal(t, fingerprint("browser"), store.read(cookies[i].Value).userAgent)
+}
+
+func TestRememberMeSessionLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy SessionLimitPolicy
+	}{
+		{"reject new", LimitRejectNew},
+		{"evict oldest", LimitEvictOldest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewInMemorySessionStore()
+			sm := NewSessionManager(WithStore(store), WithRememberMe(time.Hour), WithMaxSessionsPerUser(1, tt.policy))
+			t.Cleanup(func() { sm.Close() })
+			router := gin.New()
+			router.Use(sm.Handle())
+			router.GET("/login", func(c *gin.Context) {
+				assert.NoError(t, sm.Login(c, "alice"))
+				assert.NoError(t, sm.Remember(c, "alice"))
+			})
+			router.GET("/me", func(c *gin.Context) {
+				user, _ := GetSession(c).Get(UserIDKey).(string)
+				c.String(http.StatusOK, user)
+			})
+			do := func(path string, cookies ...*http.Cookie) (string, map[string]*http.Cookie) {
+				rw := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				for _, cookie := range cookies {
+					req.AddCookie(cookie)
+				}
+				router.ServeHTTP(rw, req)
+				set := map[string]*http.Cookie{}
+				for _, cookie := range rw.Result().Cookies() {
+					set[cookie.Name] = cookie
+				}
+				return rw.Body.String(), set
+			}
+
+			_, cookies := do("/login")
+			first := cookies["session"]
+			// another client holding the remember-me cookie logs in while the first session is alive
+			user, cookies := do("/me", cookies["session_remember"])
+			if tt.policy == LimitRejectNew {
+				assert.Empty(t, user)
+				assert.NotContains(t, cookies, "session_remember")
+				assert.NotNil(t, store.read(first.Value))
+			} else {
+				assert.Equal(t, "alice", user)
+				assert.Contains(t, cookies, "session_remember")
+				assert.Nil(t, store.read(first.Value))
+			}
+			keys, err := sm.userIndex.UserSessions("alice")
+			assert.NoError(t, err)
+			assert.Len(t, keys, 1)
+		})
+	}
+}
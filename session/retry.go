@@ -0,0 +1,108 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// WithStoreRetry retries failed writes, destroys and collections of the store up to attempts times in
+// total, waiting backoff before the first retry and twice as long before each next one, so transient
+// failures of remote stores do not reach the users. retryable decides which errors are transient; if it
+// is nil every error is, except for version conflicts and sessions exceeding their quota.
+func WithStoreRetry(attempts int, backoff time.Duration, retryable func(error) bool) Option {
+	return func(s *SessionManager) {
+		s.retryAttempts = attempts
+		s.retryBackoff = backoff
+		s.retryable = retryable
+	}
+}
+
+// useStoreRetry wraps the store for WithStoreRetry.
+func (m *SessionManager) useStoreRetry() {
+	if m.retryAttempts <= 1 {
+		return
+	}
+	retryable := m.retryable
+	if retryable == nil {
+		retryable = func(err error) bool {
+			return !errors.Is(err, ErrVersionConflict) && !errors.Is(err, ErrSessionTooLarge)
+		}
+	}
+	m.store = &retryStore{inner: m.store, attempts: m.retryAttempts, backoff: m.retryBackoff, retryable: retryable}
+}
+
+// retryStore retries the failed operations of the inner store.
+type retryStore struct {
+	inner     SessionStore
+	attempts  int
+	backoff   time.Duration
+	retryable func(error) bool
+}
+
+// retry runs op until it succeeds, fails with an error that is not retryable or ran out of attempts.
+func (s *retryStore) retry(op func() error) error {
+	delay := s.backoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= s.attempts || !s.retryable(err) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (s *retryStore) read(id string) *Session {
+	return s.inner.read(id)
+}
+
+func (s *retryStore) readChecked(id string) (*Session, error) {
+	return readStore(s.inner, id)
+}
+
+func (s *retryStore) write(session *Session) error {
+	return s.retry(func() error { return writeStore(s.inner, session) })
+}
+
+func (s *retryStore) destroy(id string) error {
+	return s.retry(func() error { return s.inner.destroy(id) })
+}
+
+func (s *retryStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	return s.retry(func() error { return s.inner.gc(idleExpiration, absoluteExpiration, expired) })
+}
+
+func (s *retryStore) setGCBudget(maxDuration time.Duration, maxDeletes int) {
+	if store, ok := s.inner.(gcBudgeter); ok {
+		store.setGCBudget(maxDuration, maxDeletes)
+	}
+}
+
+func (s *retryStore) sharesSessions() bool {
+	return sharesSessions(s.inner)
+}
+
+func (s *retryStore) invalidate(key string) {
+	invalidate(s.inner, key)
+}
+
+func (s *retryStore) each(fn func(*Session) error) error {
+	return eachInner(s.inner, fn)
+}
+
+func (s *retryStore) count() int {
+	return storeCount(s.inner)
+}
+
+func (s *retryStore) unwrap() SessionStore {
+	return s.inner
+}
+
+// Close closes the inner store if it is an io.Closer.
+func (s *retryStore) Close() error {
+	if closer, ok := s.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errBlip = errors.New("connection reset")
+
+// blipStore fails writes and destroys until failures is used up.
+type blipStore struct {
+	*inMemorySessionStore
+	failures atomic.Int32
+	calls    atomic.Int32
+}
+
+func (s *blipStore) fail() error {
+	s.calls.Add(1)
+	if s.failures.Add(-1) >= 0 {
+		return errBlip
+	}
+	return nil
+}
+
+func (s *blipStore) write(session *Session) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.inMemorySessionStore.write(session)
+}
+
+func (s *blipStore) destroy(id string) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.inMemorySessionStore.destroy(id)
+}
+
+func TestStoreRetry(t *testing.T) {
+	store := &blipStore{inMemorySessionStore: NewInMemorySessionStore()}
+	sm := NewSessionManager(WithStore(store), WithStoreRetry(3, time.Millisecond, nil))
+	t.Cleanup(func() { sm.Close() })
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+
+	store.failures.Store(2)
+	assert.NoError(t, sm.save(sess, nil))
+	assert.Equal(t, int32(3), store.calls.Load())
+	assert.NotNil(t, store.read(sess.id))
+
+	store.calls.Store(0)
+	store.failures.Store(3)
+	assert.ErrorIs(t, sm.store.destroy(sess.id), errBlip)
+	assert.Equal(t, int32(3), store.calls.Load())
+
+	// Version conflicts are not retried.
+	stale := sess.withData(sess.values())
+	stale.version = 0
+	store.calls.Store(0)
+	assert.ErrorIs(t, sm.store.write(stale), ErrVersionConflict)
+	assert.Equal(t, int32(1), store.calls.Load())
+}
+
+func TestStoreRetryable(t *testing.T) {
+	store := &blipStore{inMemorySessionStore: NewInMemorySessionStore()}
+	sm := NewSessionManager(WithStore(store), WithStoreRetry(3, time.Millisecond, func(err error) bool { return false }))
+	t.Cleanup(func() { sm.Close() })
+
+	store.failures.Store(1)
+	assert.ErrorIs(t, sm.store.destroy("id"), errBlip)
+	assert.Equal(t, int32(1), store.calls.Load())
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"slices"
+)
+
+// Migration upgrades session data from one schema version to the next by modifying data.
+type Migration func(data map[string]any) error
+
+// WithSchemaMigrations versions the shape of the session data: migrations[i] upgrades data of
+// version i to version i+1, so the current version is len(migrations) and sessions saved before
+// the option was used have version 0. The version is kept in the session metadata, not in its
+// data. Sessions are upgraded lazily when they are read, and saved with the current version.
+// Append a migration when the data changes in a deploy; sessions whose migration fails are
+// dropped like unknown ones. Sessions of a newer version are left unchanged.
+func WithSchemaMigrations(migrations ...Migration) Option {
+	return func(s *SessionManager) {
+		s.migrations = migrations
+	}
+}
+
+// upgrade applies the migrations the session misses and reports whether it succeeded.
+func (m *SessionManager) upgrade(session *Session) bool {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.schema >= len(m.migrations) {
+		return true
+	}
+	data := session.values()
+	var err error
+	version := session.schema
+	for ; err == nil && version < len(m.migrations); version++ {
+		err = m.migrations[version](data)
+	}
+	if err != nil {
+		m.logger.Error("migrating session failed", "session", hashID(session.id), "version", version, "error", err)
+		return false
+	}
+
+	session.schema = len(m.migrations)
+	for _, key := range session.keys {
+		if _, ok := data[key]; !ok {
+			session.keys = removeKey(session.keys, key)
+			session.markChanged(key)
+		}
+	}
+	for key := range data {
+		if !slices.Contains(session.keys, key) {
+			session.keys = append(session.keys, key)
+		}
+		session.markChanged(key)
+	}
+	session.setData(data)
+	return true
+}
+
+// schemaVersion returns the schema version of the session data.
+func (s *Session) schemaVersion() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.schema
+}
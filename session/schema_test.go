@@ -0,0 +1,96 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaMigrations(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store), WithSchemaMigrations(
+		func(data map[string]any) error {
+			data["first_name"] = data["name"]
+			delete(data, "name")
+			return nil
+		},
+		func(data map[string]any) error {
+			if data["first_name"] == "broken" {
+				return errors.New("cannot migrate")
+			}
+			data["locale"] = "en"
+			return nil
+		},
+	))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		sess.Put("visits", 1)
+		c.JSON(http.StatusOK, gin.H{"first_name": sess.Get("first_name"), "locale": sess.Get("locale")})
+	})
+	do := func(id string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: id})
+		}
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	old, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	old.Put("name", "alice")
+	assert.NoError(t, store.write(old))
+	rw := do(old.id)
+	assert.JSONEq(t, `{"first_name": "alice", "locale": "en"}`, rw.Body.String())
+	stored := store.read(old.id)
+	assert.Equal(t, 2, stored.schemaVersion())
+	assert.ElementsMatch(t, []string{"first_name", "locale", "visits"}, stored.Keys())
+
+	broken, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	broken.Put("name", "broken")
+	assert.NoError(t, store.write(broken))
+	rw = do(broken.id)
+	assert.JSONEq(t, `{"first_name": null, "locale": null}`, rw.Body.String())
+	assert.NotEqual(t, broken.id, rw.Result().Cookies()[0].Value)
+
+	rw = do("")
+	created := store.read(rw.Result().Cookies()[0].Value)
+	assert.Equal(t, 2, created.schemaVersion())
+	assert.Equal(t, []string{"visits"}, created.Keys())
+}
+
+func TestSchemaVersionStored(t *testing.T) {
+	migrated := 0
+	migrate := func(data map[string]any) error {
+		migrated++
+		return nil
+	}
+	store := NewFileStore(filepath.Join(t.TempDir(), "sessions.json"))
+	sm := NewSessionManager(WithStore(store), WithSchemaMigrations(migrate),
+		WithMaxSessionBytes(64), WithQuotaPolicy(QuotaEvictOldest))
+	t.Cleanup(func() { sm.Close() })
+
+	sess, err := sm.Create(context.Background())
+	assert.NoError(t, err)
+	sess.Put("a", strings.Repeat("a", 40))
+	sess.Put("b", strings.Repeat("b", 40))
+	assert.NoError(t, sm.Commit(context.Background(), sess))
+
+	loaded, err := sm.Load(context.Background(), sess.ID())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, migrated, "new sessions have the current version")
+	assert.Equal(t, 1, loaded.schemaVersion())
+	assert.Equal(t, []string{"b"}, loaded.Keys(), "the quota cannot evict the version")
+}
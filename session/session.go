@@ -3,34 +3,79 @@
 package session
 
 import (
+	"bufio"
+	"context"
+	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"maps"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Session struct {
-	mu             sync.RWMutex
-	createdAt      time.Time
-	lastActivityAt time.Time
+	mu        sync.RWMutex
+	createdAt time.Time
+	// lastActivityAt holds Unix nanoseconds, it is updated without locking on every read.
+	lastActivityAt atomic.Int64
 	id             string
-	data           *sync.Map
+	// data is copy-on-write: a stored map is never modified, writers replace it with a modified
+	// copy under mu. Readers of hot sessions thereby neither lock nor wait for each other.
+	data atomic.Pointer[map[string]any]
+	keys []string
+	// fresh is set for sessions created by this request that have not been written to yet.
+	// They are neither persisted nor announced by a cookie.
+	fresh bool
+	// version of the stored session this one was loaded from, see ErrVersionConflict.
+	version uint64
+	// queued is set once a write of the session was queued by a store writing later, see WithAsyncWrites
+	// and WithWriteCoalescing.
+	queued bool
+	// schema is the version of the shape of the data, see WithSchemaMigrations.
+	schema int
+	// changed holds the keys put or deleted since the session was last saved.
+	changed map[string]struct{}
+	// base is the data of the last save, see rollback.
+	base *map[string]any
+	// cookieIssuedAt is the last time a cookie carrying the session id was sent, see WithCookieRefreshThreshold.
+	cookieIssuedAt time.Time
+	// key identifies the session in the store if it differs from id, see WithHashedIDs.
+	key string
+	// ip is the client address the session was created from, see WithBindToIP.
+	ip string
+	// userAgent is the fingerprint of the User-Agent the session is bound to, see WithBindToUserAgent.
+	userAgent string
+	// agent is the User-Agent the session was created with.
+	agent string
+	// degraded is set for sessions kept in memory while the store failed, see WithDegradedMode.
+	degraded atomic.Bool
+	// lazy holds the undecoded data of sessions read from a decoding store.
+	lazy *lazyData
+	// clock is the one of the manager that created the session, see WithClock.
+	clock Clock
+	// generation counts the releases to the pool, see WithSessionPooling.
+	generation atomic.Uint64
+	released   atomic.Bool
 }
 
 type SessionStore interface {
 	read(id string) *Session
 	write(session *Session) error
 	destroy(id string) error
-	gc(idleExpiration, absoluteExpiration time.Duration) error
+	// gc removes expired sessions and passes each of them to expired.
+	gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error
 }
 
 type SessionManager struct {
@@ -38,30 +83,177 @@ type SessionManager struct {
 	idleExpiration     time.Duration
 	absoluteExpiration time.Duration
 	cookieName         string
+	cookiePrefix       CookiePrefix
+	signingKeys        [][]byte
+	aeads              []cipher.AEAD
 	validationTicker   *time.Ticker
+	gcMaxDuration      time.Duration
+	gcMaxDeletes       int
+	gcJitter           float64
+	clock              Clock
+	asyncQueueSize     int
+	asyncFlushInterval time.Duration
+	reads              *readGroup
+	coalesceWindow     time.Duration
+	compressor         Compressor
+	compressMinSize    int
+	pooling            bool
+	metrics            *Metrics
+	tracer             Tracer
+	logger             Logger
+	events             *eventStream
+	gcObserver         func(GCStats)
+	expvar             bool
+	expvarNamespace    string
+	gcTotals           *gcTotals
+	invalidator        Invalidator
+	unsubscribe        func()
+	gcLeader           GCLeader
+	degradedMode       bool
+	degrading          *degradingStore
+	retryAttempts      int
+	retryBackoff       time.Duration
+	retryable          func(error) bool
+	capacity           *capacityAlarm
+	migrations         []Migration
 	domain             string
+	path               string
+	sameSite           http.SameSite
+	secure             bool
+	httpOnly           bool
+	partitioned        bool
+	maxSessionBytes    int
+	quotaPolicy        QuotaPolicy
+	skipper            func(*gin.Context) bool
+	errorHandler       func(*gin.Context, error)
+	saveOnAbort        bool
+	refreshThreshold   time.Duration
+	httpErrorHandler   func(http.ResponseWriter, *http.Request, error)
+	contextKey         string
+	hooks              Hooks
+	conflictRetries    int
+	merge              MergeFunc
+	idGenerator        func() (string, error)
+	idLength           int
+	idValidator        func(string) bool
+	authKeys           []string
+	hashIDs            bool
+	auditSink          AuditSink
+	varyCookie         bool
+	cookieExpiry       bool
+	jwt                bool
+	domainFunc         func(*http.Request) string
+	userIndex          UserIndex
+	maxSessionsPerUser int
+	sessionLimitPolicy SessionLimitPolicy
+	rememberTTL        time.Duration
+	rememberStore      RememberStore
+	transports         []Transport
+	cacheControl       string
+	bindToIP           bool
+	bindToUserAgent    bool
+	ipv4Bits           int
+	ipv6Bits           int
+	trustedProxies     []netip.Prefix
+	invalidIDLimit     int
+	invalidIDWindow    time.Duration
+	invalidIDs         *attemptTracker
+	locker             *idLocker
+	closeOnce          sync.Once
+	closed             chan struct{}
+	gcDone             chan struct{}
 }
 
 type sessionContextWriter struct {
+	gin.ResponseWriter
+	cookieWriter
+}
+
+// cookieWriter writes the session cookie once, before the response headers are flushed.
+type cookieWriter struct {
 	sessionManager *SessionManager
-	c              *gin.Context
+	state          *requestState
 	done           bool
-	domain         string
+	// sent is set once the response headers were written, cookies cannot be added afterwards.
+	sent bool
+	// unlock releases the lock of WithSessionLocking early for hijacked connections.
+	unlock func()
 }
 
+var _ gin.ResponseWriter = (*sessionContextWriter)(nil)
+
 type expSession struct {
 	Id             string
 	Data           map[string]any
 	CreatedAt      time.Time
 	LastActivityAt time.Time
+	Version        uint64
+	Schema         int
+	CookieIssuedAt time.Time
+	IP             string
+	UserAgent      string
+	Agent          string
+}
+
+// exported returns the stored form of session.
+func exported(session *Session) expSession {
+	return expSession{
+		Id:             session.storeKey(),
+		Data:           session.values(),
+		CreatedAt:      session.createdAt,
+		LastActivityAt: session.getLastActivity(),
+		Version:        session.Version(),
+		Schema:         session.schemaVersion(),
+		CookieIssuedAt: session.getCookieIssuedAt(),
+		IP:             session.ip,
+		UserAgent:      session.userAgent,
+		Agent:          session.agent,
+	}
+}
+
+// session returns the session of the stored form.
+func (e expSession) session() *Session {
+	keys := make([]string, 0, len(e.Data))
+	data := make(map[string]any, len(e.Data))
+	for k, v := range e.Data {
+		data[k] = v
+		keys = append(keys, k)
+	}
+	session := &Session{
+		id:             e.Id,
+		createdAt:      e.CreatedAt,
+		keys:           keys,
+		version:        e.Version,
+		schema:         e.Schema,
+		cookieIssuedAt: e.CookieIssuedAt,
+		ip:             e.IP,
+		userAgent:      e.UserAgent,
+		agent:          e.Agent,
+	}
+	session.setData(data)
+	session.setLastActivity(e.LastActivityAt)
+	return session
 }
+
 type Option func(*SessionManager)
 
-var logger = func() *log.Logger {
-	logger := log.Default()
-	logger.SetPrefix("[gin-memory-sessions-go]")
-	return logger
-}()
+// QuotaPolicy decides what happens when a session exceeds the size set by WithMaxSessionBytes.
+type QuotaPolicy int
+
+const (
+	// QuotaReject fails the save and leaves the previously stored session untouched. Stores sharing
+	// the session with the requests, like the in-memory store, get the keys changed since the last
+	// save restored.
+	QuotaReject QuotaPolicy = iota
+	// QuotaEvictOldest removes the least recently written keys until the session fits.
+	QuotaEvictOldest
+)
+
+// ErrSessionTooLarge is returned by a save when the serialized session exceeds the configured quota.
+var ErrSessionTooLarge = errors.New("session exceeds maximum size")
+
+// ErrSessionNotFound is passed to the error handler when GetSession is called outside the middleware.
+var ErrSessionNotFound = errors.New("session not found in request context")
 
 func WithStore(store SessionStore) Option {
 	return func(s *SessionManager) {
@@ -95,69 +287,302 @@ func WithCookieDomain(domain string) Option {
 	}
 }
 
+// WithCookieDomainFunc chooses the cookie domain per request, e.g. ".example.com" to share sessions
+// between the subdomains of a tenant and "" for host only cookies on vanity domains.
+// It takes precedence over WithCookieDomain.
+func WithCookieDomainFunc(domain func(r *http.Request) string) Option {
+	return func(s *SessionManager) {
+		s.domainFunc = domain
+	}
+}
+
+// WithCookiePath restricts the cookie to path, e.g. for applications mounted below a sub path. Defaults to "/".
+func WithCookiePath(path string) Option {
+	return func(s *SessionManager) {
+		if !strings.HasPrefix(path, "/") {
+			panic(errors.New("cookie path must start with /"))
+		}
+		s.path = path
+	}
+}
+
+// WithSameSite sets the SameSite attribute of the cookie. Defaults to http.SameSiteLaxMode,
+// http.SameSiteDefaultMode omits the attribute.
+func WithSameSite(sameSite http.SameSite) Option {
+	return func(s *SessionManager) {
+		s.sameSite = sameSite
+	}
+}
+
+// WithCookieSecure sets the Secure attribute of the cookie. Defaults to true; browsers do not send
+// secure cookies over plain HTTP, so local development without TLS needs false.
+func WithCookieSecure(secure bool) Option {
+	return func(s *SessionManager) {
+		s.secure = secure
+	}
+}
+
+// WithCookieHTTPOnly sets the HttpOnly attribute of the cookie. Defaults to true, which hides the
+// session id from JavaScript.
+func WithCookieHTTPOnly(httpOnly bool) Option {
+	return func(s *SessionManager) {
+		s.httpOnly = httpOnly
+	}
+}
+
 func WithValidationTicker(ticker *time.Ticker) Option {
 	return func(s *SessionManager) {
 		s.validationTicker = ticker
 	}
 }
 
-func generateSessionID() string {
-	id := make([]byte, 32)
+// WithSkipper makes the middleware pass requests straight to the next handler when skipper returns true.
+// Skipped requests have no session attached, so GetSession panics for them.
+func WithSkipper(skipper func(*gin.Context) bool) Option {
+	return func(s *SessionManager) {
+		s.skipper = skipper
+	}
+}
+
+// WithErrorHandler is called with store failures, session id generation failures and
+// missing sessions instead of logging or panicking. It may abort the request with a status
+// of its choice; requests that cannot be served without a session are aborted with a 500 otherwise.
+func WithErrorHandler(handler func(*gin.Context, error)) Option {
+	return func(s *SessionManager) {
+		s.errorHandler = handler
+	}
+}
+
+// WithCookieRefreshThreshold only resends the cookie once its remaining lifetime drops below threshold,
+// instead of on every response. A new or regenerated session id is always sent.
+func WithCookieRefreshThreshold(threshold time.Duration) Option {
+	return func(s *SessionManager) {
+		s.refreshThreshold = threshold
+	}
+}
+
+// WithMaxSessionBytes limits the JSON encoded size of a session's data. A value of 0 disables the check.
+func WithMaxSessionBytes(n int) Option {
+	return func(s *SessionManager) {
+		if n < 0 {
+			panic(errors.New("max session bytes cannot be negative"))
+		}
+		s.maxSessionBytes = n
+	}
+}
+
+// WithQuotaPolicy selects how sessions exceeding WithMaxSessionBytes are handled. Defaults to QuotaReject.
+func WithQuotaPolicy(policy QuotaPolicy) Option {
+	return func(s *SessionManager) {
+		s.quotaPolicy = policy
+	}
+}
+
+func generateSessionID() (string, error) {
+	return randomID(32)
+}
+
+func randomID(n int) (string, error) {
+	id := make([]byte, n)
 
 	_, err := io.ReadFull(rand.Reader, id)
 	if err != nil {
-		panic("failed to generate session id")
+		return "", fmt.Errorf("failed to generate session id: %w", err)
 	}
 
-	return base64.RawURLEncoding.EncodeToString(id)
+	return base64.RawURLEncoding.EncodeToString(id), nil
 }
 
-func newSession() *Session {
-	return &Session{
-		id:             generateSessionID(),
-		data:           &sync.Map{},
-		createdAt:      time.Now(),
-		lastActivityAt: time.Now(),
+func newSession(generate func() (string, error)) (*Session, error) {
+	id, err := generate()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		id:        id,
+		createdAt: time.Now(),
+		fresh:     true,
 	}
+	session.setData(make(map[string]any))
+	session.touch()
+	return session, nil
 }
 
 func GetGenericValue[T any](session *Session, key string) (T, error) {
-	session.touch()
-	if val, ok := session.data.Load(key); ok {
+	if val, ok := session.load(key); ok {
 		return val.(T), nil
 	}
 	return *new(T), fmt.Errorf("no value found for key: %s", key)
 }
 
+// ID returns the session id sent to the client.
+func (s *Session) ID() string {
+	return s.id
+}
+
 func (s *Session) Get(key string) any {
-	s.touch()
-	if val, ok := s.data.Load(key); ok {
-		return val
-	}
-	return nil
+	val, _ := s.load(key)
+	return val
 }
 
 func (s *Session) Put(key string, value any) {
-	s.touch()
-	s.data.Store(key, value)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.putLocked(key, value)
 }
 
 func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteLocked(key)
+}
+
+// putLocked sets a value and records the change. It must be called with the write lock held.
+func (s *Session) putLocked(key string, value any) {
+	s.checkReleased()
+	s.touch()
+	s.fresh = false
+	s.mutate(func(data map[string]any) { data[key] = value })
+	s.keys = append(removeKey(s.keys, key), key)
+	s.markChanged(key)
+}
+
+// deleteLocked removes a value and records the change. It must be called with the write lock held.
+func (s *Session) deleteLocked(key string) {
+	s.checkReleased()
 	s.touch()
-	s.data.Delete(key)
+	s.mutate(func(data map[string]any) { delete(data, key) })
+	s.keys = removeKey(s.keys, key)
+	s.markChanged(key)
+}
+
+// Keys returns the keys of the session in the order they were last put.
+func (s *Session) Keys() []string {
+	s.resolve()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.keys...)
+}
+
+// Version returns the number of times the session has been saved.
+func (s *Session) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// markChanged must be called with the write lock held.
+func (s *Session) markChanged(key string) {
+	if s.changed == nil {
+		s.changed = make(map[string]struct{})
+	}
+	s.changed[key] = struct{}{}
+	if s.lazy != nil {
+		s.lazy.changed.Store(true)
+	}
+}
+
+// load reads a value and records the activity without locking.
+func (s *Session) load(key string) (any, bool) {
+	s.checkReleased()
+	s.touch()
+	val, ok := s.snapshot()[key]
+	return val, ok
+}
+
+// snapshot returns the current session data. The map must not be modified.
+func (s *Session) snapshot() map[string]any {
+	s.resolve()
+	if data := s.data.Load(); data != nil {
+		return *data
+	}
+	return nil
+}
+
+// setData replaces the session data. data must not be modified afterwards.
+func (s *Session) setData(data map[string]any) {
+	s.data.Store(&data)
+}
+
+// mutate applies change to a copy of the session data and publishes the copy.
+// It must be called with the write lock held.
+func (s *Session) mutate(change func(data map[string]any)) {
+	data := maps.Clone(s.snapshot())
+	if data == nil {
+		data = make(map[string]any)
+	}
+	change(data)
+	s.setData(data)
+}
+
+// values copies the session data into a plain map, e.g. for serialization.
+func (s *Session) values() map[string]any {
+	return maps.Clone(s.snapshot())
+}
+
+// size returns the length of the JSON encoded session data.
+func (s *Session) size() (int, error) {
+	data, err := json.Marshal(s.values())
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// evictOldest removes the least recently written key and reports whether one was removed.
+func (s *Session) evictOldest() bool {
+	s.resolve()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.keys) == 0 {
+		return false
+	}
+	oldest := s.keys[0]
+	s.mutate(func(data map[string]any) { delete(data, oldest) })
+	s.markChanged(s.keys[0])
+	s.keys = s.keys[1:]
+	return true
+}
+
+func removeKey(keys []string, key string) []string {
+	for i, k := range keys {
+		if k == key {
+			return append(keys[:i], keys[i+1:]...)
+		}
+	}
+	return keys
 }
 
 func (s *Session) touch() {
+	s.setLastActivity(s.now())
+}
+
+func (s *Session) setLastActivity(t time.Time) {
+	s.lastActivityAt.Store(t.UnixNano())
+}
+
+func (s *Session) isFresh() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fresh
+}
+
+func (s *Session) getCookieIssuedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cookieIssuedAt
+}
+
+func (s *Session) setCookieIssuedAt(t time.Time) {
 	s.mu.Lock()
-	s.lastActivityAt = time.Now()
-	s.mu.Unlock()
+	defer s.mu.Unlock()
+	s.cookieIssuedAt = t
 }
 
 func (s *Session) getLastActivity() time.Time {
-	s.mu.RLock()
-	t := s.lastActivityAt
-	s.mu.RUnlock()
-	return t
+	return time.Unix(0, s.lastActivityAt.Load())
 }
 
 func NewSessionManager(opts ...Option) *SessionManager {
@@ -167,36 +592,160 @@ func NewSessionManager(opts ...Option) *SessionManager {
 		absoluteExpiration: time.Hour,
 		cookieName:         "session",
 		domain:             "",
+		path:               "/",
+		sameSite:           http.SameSiteLaxMode,
+		secure:             true,
+		httpOnly:           true,
 		validationTicker:   time.NewTicker(time.Minute * 5),
+		saveOnAbort:        true,
+		conflictRetries:    3,
+		merge:              MergeChanges,
+		idLength:           32,
+		varyCookie:         true,
+		cacheControl:       `no-cache="Set-Cookie"`,
+		closed:             make(chan struct{}),
+		gcDone:             make(chan struct{}),
 	}
 
 	for _, opt := range opts {
 		opt(m)
 	}
 
+	if m.idGenerator == nil {
+		m.useRandomIDs()
+	}
+	if m.invalidIDLimit > 0 || m.hooks.OnInvalidID != nil {
+		if m.invalidIDWindow <= 0 {
+			m.invalidIDWindow = time.Minute
+		}
+		m.invalidIDs = newAttemptTracker(m.invalidIDWindow, m.now)
+	}
+	m.cookieName = string(m.cookiePrefix) + m.cookieName
+	if m.rememberStore == nil {
+		m.rememberStore = NewInMemoryRememberStore()
+	}
+	m.useLogger()
+	m.useClock()
+	m.useStoreRetry()
+	m.useDegradedMode()
+	m.bindTransports()
+	m.useUserIndex()
+	m.useGCBudget()
+	m.useCompression()
+	m.useWriteCoalescing()
+	m.useAsyncWrites()
+	m.useExpvar()
+	m.useMetrics()
+	m.useEvents()
+	m.useCapacityAlarm()
+	m.useInvalidator()
+	m.usePooling()
+	if err := m.checkCookie(); err != nil {
+		panic(err)
+	}
+	if err := m.checkJWT(); err != nil {
+		panic(err)
+	}
+
 	go m.gc(m.validationTicker)
 
 	return m
 }
 
 func (m *SessionManager) gc(t *time.Ticker) {
-	for range t.C {
-		err := m.store.gc(m.idleExpiration, m.absoluteExpiration)
-		if err != nil {
-			panic(err)
+	defer close(m.gcDone)
+
+	last := time.Now()
+	for {
+		select {
+		case tick, ok := <-t.C:
+			if !ok {
+				return
+			}
+			if delay := jitter(tick.Sub(last), m.gcJitter); delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-m.closed:
+					return
+				}
+			}
+			interval := tick.Sub(last)
+			last = tick
+			if !m.leadsGC(interval) {
+				continue
+			}
+			_, err := m.RunGC(context.Background())
+			if err != nil {
+				m.logger.Error("session gc failed", "error", err)
+			}
+		case <-m.closed:
+			return
 		}
 	}
 }
 
+// Close stops the garbage collection, runs a final collection and closes the store if it
+// implements io.Closer, giving it the chance to flush pending writes. Calling Close more than once is a no-op.
+func (m *SessionManager) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		m.validationTicker.Stop()
+		close(m.closed)
+		<-m.gcDone
+
+		_, err = m.RunGC(context.Background())
+		if closer, ok := m.store.(io.Closer); ok {
+			err = errors.Join(err, closer.Close())
+		}
+		if m.unsubscribe != nil {
+			m.unsubscribe()
+		}
+		if m.events != nil {
+			m.events.close()
+		}
+	})
+	return err
+}
+
+// Shutdown is Close for graceful shutdowns: it writes the sessions queued by WithAsyncWrites and
+// WithWriteCoalescing to the store, but returns ctx.Err() if that does not finish in time. Sessions
+// saved by requests still running afterwards are written directly. Call it after the HTTP server
+// was shut down, e.g. on SIGTERM.
+func (m *SessionManager) Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Close()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// expired reports a session removed because of its expiration.
+func (m *SessionManager) expired(session *Session) {
+	m.unindex(session)
+	m.hooks.expire(session)
+	m.audit(AuditExpired, session, nil)
+	if m.pooling {
+		releaseSession(session)
+	}
+}
+
 func (m *SessionManager) validate(session *Session) bool {
-	if time.Since(session.createdAt) > m.absoluteExpiration ||
-		time.Since(session.getLastActivity()) > m.idleExpiration {
+	now := m.now()
+	if now.Sub(session.createdAt) > m.absoluteExpiration ||
+		now.Sub(session.getLastActivity()) > m.idleExpiration {
 
 		// Delete the session from the store
-		err := m.store.destroy(session.id)
+		err := m.store.destroy(session.storeKey())
 		if err != nil {
 			return false
 		}
+		m.logSession("session expired", session)
+		m.expired(session)
 
 		return false
 	}
@@ -204,64 +753,341 @@ func (m *SessionManager) validate(session *Session) bool {
 	return true
 }
 
-func (m *SessionManager) start(c *gin.Context) (*Session, *gin.Context) {
+func (m *SessionManager) start(r *http.Request, state *requestState) (*Session, error) {
 	var session *Session
 
 	// Read From Cookie
-	cookie, err := c.Cookie(m.cookieName)
-	if err == nil {
-		session = m.store.read(cookie)
+	if id, ok := m.readID(r); ok {
+		span := m.startSpan(r.Context(), "session.read")
+		session, state.corrupt = m.readChecked(id)
+		span.set("session.hit", session != nil)
+		span.end(state.corrupt)
+		if session != nil {
+			m.logSession("session loaded", session)
+		} else {
+			m.logger.Debug("session not found", "session", hashID(id))
+		}
+	}
+	if session == nil && m.hasCookie(r) {
+		err := m.rejectInvalidID(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if session != nil && (!m.validate(session) || !m.checkBinding(r, session)) {
+		session = nil
+	}
+	// Continue a remembered login
+	if session == nil {
+		var err error
+		session, err = m.remember(r, state)
+		if err != nil {
+			return nil, err
+		}
 	}
 	// Generate a new session
-	if session == nil || !m.validate(session) {
-		session = newSession()
+	if session == nil {
+		var err error
+		session, err = m.newSession()
+		if err != nil {
+			return nil, err
+		}
+		m.bind(r, session)
+		m.logSession("session started", session)
 	}
 	// Attach session to context
-	c.Set("session", session)
+	state.set(session)
+
+	return session, nil
+}
+
+// lock serializes requests of the same session if WithSessionLocking is enabled.
+// The returned function releases the lock.
+func (m *SessionManager) lock(r *http.Request) func() {
+	if m.locker == nil {
+		return func() {}
+	}
+	id, ok := m.readID(r)
+	if !ok {
+		return func() {}
+	}
+	return m.locker.lock(id)
+}
+
+// finish persists the session attached to the request, handlers may have replaced it. Sessions
+// started by the request are not saved if the response was written before their cookie, as the
+// client could never send them again.
+func (m *SessionManager) finish(state *requestState) error {
+	session, ok := state.get()
+	if !ok {
+		panic("session not found in request context")
+	}
+	if state.lostCookie() && !session.isFresh() && !session.isStored() {
+		m.logger.Error("session not saved, its cookie could not be sent after the response was written",
+			"session", hashID(session.id))
+		return nil
+	}
+	return m.save(session, state.request)
+}
+
+func (m *SessionManager) handleError(c *gin.Context, err error) {
+	if m.errorHandler != nil {
+		m.errorHandler(c, err)
+		return
+	}
 
-	return session, c
+	m.logger.Error("session middleware failed", "error", err)
+	_ = c.Error(err)
 }
 
-func (m *SessionManager) save(session *Session) error {
+// save persists the session; r is the request it belongs to, if any.
+func (m *SessionManager) save(session *Session, r *http.Request) (err error) {
+	// Sessions are only created on the first write
+	if session.isFresh() {
+		m.logSession("session not saved, no data written", session)
+		return nil
+	}
+
+	span := m.startSpan(requestContext(r), "session.write")
+	defer func() { span.end(err) }()
+	if m.tracer != nil {
+		if size, err := session.size(); err == nil {
+			span.set("session.size", size)
+		}
+	}
+
 	session.touch()
+	created := !session.isStored()
+
+	err = m.enforceQuota(session)
+	if err != nil {
+		if errors.Is(err, ErrSessionTooLarge) && sharesSessions(m.store) && session.isStored() {
+			// The store holds this very session, the rejected values must not stay visible.
+			session.rollback()
+		}
+		return err
+	}
 
-	err := m.store.write(session)
+	err = writeStore(m.store, session)
+	if err == nil && created {
+		m.logSession("session created", session)
+		m.hooks.create(session)
+		m.audit(AuditCreated, session, r)
+	}
+	for attempt := 0; errors.Is(err, ErrVersionConflict) && attempt < m.conflictRetries; attempt++ {
+		m.logSession("session version conflict, merging", session, "attempt", attempt+1)
+		session, err = m.resolveConflict(session)
+		if err != nil {
+			return err
+		}
+		err = writeStore(m.store, session)
+	}
 	if err != nil {
 		return err
 	}
+	m.logSession("session saved", session, "version", session.Version())
+	m.emit(EventSaved, session, "")
 
 	return nil
 }
 
+func (m *SessionManager) enforceQuota(session *Session) error {
+	if m.maxSessionBytes == 0 {
+		return nil
+	}
+
+	for {
+		size, err := session.size()
+		if err != nil {
+			return err
+		}
+		if size <= m.maxSessionBytes {
+			return nil
+		}
+		if m.quotaPolicy != QuotaEvictOldest || !session.evictOldest() {
+			return fmt.Errorf("%w: %d > %d bytes", ErrSessionTooLarge, size, m.maxSessionBytes)
+		}
+	}
+}
+
 func (m *SessionManager) Handle() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var state *requestState
+		c.Request, state = m.attach(c.Request)
+
+		if m.skipper != nil && m.skipper(c) {
+			c.Next()
+			return
+		}
+
+		// Serialize requests of the same session until it is saved
+		unlock := sync.OnceFunc(m.lock(c.Request))
+		defer unlock()
+
 		// Start the session
-		session, c := m.start(c)
+		session, err := m.start(c.Request, state)
+		if err != nil {
+			m.handleError(c, err)
+			if !c.IsAborted() {
+				c.AbortWithStatus(errorStatus(err))
+			}
+			return
+		}
+		if state.corrupt != nil && m.errorHandler != nil {
+			m.errorHandler(c, state.corrupt)
+			if c.IsAborted() {
+				return
+			}
+		}
+		m.mirror(c, session)
 
-		// Create a new response writer
+		// Wrap the response writer so the cookie is written before the response is flushed
 		sw := &sessionContextWriter{
-			sessionManager: m,
-			c:              c,
-			domain:         m.domain,
+			ResponseWriter: c.Writer,
+			cookieWriter: cookieWriter{
+				sessionManager: m,
+				state:          state,
+				unlock:         unlock,
+			},
 		}
+		c.Writer = sw
 		// Add essential headers
-		c.Header("Vary", "Cookie")
-		c.Header("Cache-Control", `no-cache="Set-Cookie"`)
-
-		// Write the session cookie to the response if not already written
-		writeCookieIfNecessary(sw)
+		m.setCacheHeaders(c.Writer.Header())
+
+		// Persist or roll back the session of panicking handlers before passing the panic on
+		sn := m.takeSnapshot(session)
+		defer func() {
+			if rec := recover(); rec != nil {
+				if sn != nil {
+					m.rollback(state, sn)
+				} else if err := m.finish(state); err != nil {
+					m.handleError(c, err)
+				}
+				panic(rec)
+			}
+		}()
 
-		// Call the next handler and pass the new response writer and new request
+		// Call the next handler with the wrapped response writer
 		c.Next()
-		err := m.save(session)
+
+		if sn != nil && c.IsAborted() {
+			m.rollback(state, sn)
+			sw.writeCookieIfNecessary(sw.ResponseWriter)
+			return
+		}
+
+		// Write the session cookie to the response if no handler wrote a response
+		sw.writeCookieIfNecessary(sw.ResponseWriter)
+
+		err = m.finish(state)
 		if err != nil {
-			logger.Println(err)
-			errr := c.Error(err)
-			if errr != nil {
-				logger.Print(errr.Error())
-			}
+			m.handleError(c, err)
+		}
+	}
+}
+
+// Regenerate moves the data of the request's session to a new id and destroys the old id,
+// e.g. to prevent session fixation after a login. It has to be called before the response is written.
+func (m *SessionManager) Regenerate(c *gin.Context) (*Session, error) {
+	session, err := m.RegenerateContext(c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+	m.mirror(c, session)
+	return session, nil
+}
+
+// RegenerateContext is Regenerate for the request context of Middleware.
+func (m *SessionManager) RegenerateContext(ctx context.Context) (*Session, error) {
+	state := stateFrom(ctx)
+	old, ok := state.get()
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	id, err := m.idGenerator()
+	if err != nil {
+		return nil, err
+	}
+
+	old.resolve()
+	old.mu.RLock()
+	session := &Session{
+		id:        id,
+		keys:      append([]string(nil), old.keys...),
+		createdAt: m.now(),
+		fresh:     old.fresh,
+		key:       m.storeKey(id),
+		version:   old.version,
+		queued:    old.queued,
+		schema:    old.schema,
+		ip:        old.ip,
+		userAgent: old.userAgent,
+		agent:     old.agent,
+		clock:     m.clock,
+	}
+	// Both sessions can share the data as it is copied on write.
+	session.setData(old.snapshot())
+	session.touch()
+	old.mu.RUnlock()
+
+	if !old.isFresh() {
+		err = m.store.destroy(old.storeKey())
+		if err != nil {
+			return nil, err
 		}
 	}
+	state.set(session)
+	if userID, ok := indexedUser(old); ok {
+		m.unindex(old)
+		err = m.userIndex.AddUserSession(userKey(userID), session.storeKey())
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.hooks.regenerate(old.id, session)
+	m.audit(AuditRegenerated, session, state.request)
+
+	return session, nil
+}
+
+// Destroy removes the request's session from the store and attaches a new, empty session to the request.
+func (m *SessionManager) Destroy(c *gin.Context) error {
+	err := m.DestroyContext(c.Request.Context())
+	if err != nil {
+		return err
+	}
+	session, _ := SessionFrom(c)
+	m.mirror(c, session)
+	return nil
+}
+
+// DestroyContext is Destroy for the request context of Middleware.
+func (m *SessionManager) DestroyContext(ctx context.Context) error {
+	state := stateFrom(ctx)
+	old, ok := state.get()
+	if !ok {
+		return ErrSessionNotFound
+	}
+	session, err := m.newSession()
+	if err != nil {
+		return err
+	}
+
+	if !old.isFresh() {
+		span := m.startSpan(ctx, "session.destroy")
+		err = m.store.destroy(old.storeKey())
+		span.end(err)
+		if err != nil {
+			return err
+		}
+		m.logSession("session destroyed", old)
+		m.unindex(old)
+		m.hooks.destroy(old)
+		m.audit(AuditDestroyed, old, state.request)
+	}
+	state.set(session)
+
+	return nil
 }
 
 type fileStore struct {
@@ -305,30 +1131,37 @@ func (f *fileStore) read(id string) *Session {
 	if err != nil {
 		return nil
 	}
-	m2 := &sync.Map{}
-	for k, v := range expS.Data {
-		m2.Store(k, v)
-	}
-	return &Session{
-		id:             expS.Id,
-		createdAt:      expS.CreatedAt,
-		lastActivityAt: expS.LastActivityAt,
-		data:           m2,
-	}
-
+	return expS.session()
 }
 
 func (f *fileStore) write(session *Session) error {
+	return f.update(session, func(map[string]any) expSession { return exported(session) })
+}
+
+// patch updates the stored data with the changed keys, the other stored values are kept.
+func (f *fileStore) patch(session *Session, puts map[string]any, deletes []string) error {
+	return f.update(session, func(prev map[string]any) expSession {
+		data, ok := prev["Data"].(map[string]any)
+		if !ok {
+			return exported(session)
+		}
+		for key, value := range puts {
+			data[key] = value
+		}
+		for _, key := range deletes {
+			delete(data, key)
+		}
+		return exported(session.withData(data))
+	})
+}
+
+// update replaces the record of session with the result of record, which gets the stored record or nil.
+func (f *fileStore) update(session *Session, record func(prev map[string]any) expSession) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	file, err := openFile(f.fileName)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
 
 	data, err := os.ReadFile(f.fileName)
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 	m := make(map[string]any)
@@ -339,146 +1172,210 @@ func (f *fileStore) write(session *Session) error {
 		}
 	}
 
-	m2 := make(map[string]any)
-	session.data.Range(func(key any, value any) bool {
-		m2[key.(string)] = value
-		return true
-	})
-
-	m[session.id] = expSession{
-		Id:             session.id,
-		Data:           m2,
-		CreatedAt:      session.createdAt,
-		LastActivityAt: session.lastActivityAt,
+	prev, _ := m[session.storeKey()].(map[string]any)
+	if prev != nil {
+		if version, _ := prev["Version"].(float64); uint64(version) != session.Version() {
+			return ErrVersionConflict
+		}
 	}
+	stored := record(prev)
+	stored.Version++
+	m[session.storeKey()] = stored
 
 	data, err = json.Marshal(m)
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(data)
+	// Rewrite the whole file, the new content may be shorter than the old one
+	err = os.WriteFile(f.fileName, data, 0660)
 	if err != nil {
 		return err
 	}
+	session.saved()
 	return nil
 }
 func (f *fileStore) destroy(id string) error {
 	return nil
 }
-func (f *fileStore) gc(idleExpiration, absoluteExpiration time.Duration) error {
-	return nil
+
+// sharesSessions is false, every read decodes the file.
+func (f *fileStore) sharesSessions() bool {
+	return false
 }
 
-type inMemorySessionStore struct {
-	mu       sync.RWMutex
-	sessions *sync.Map
+func (f *fileStore) gc(idleExpiration, absoluteExpiration time.Duration, expired func(*Session)) error {
+	return nil
 }
 
-func NewInMemorySessionStore() *inMemorySessionStore {
-	return &inMemorySessionStore{
-		mu:       sync.RWMutex{},
-		sessions: &sync.Map{},
+// SessionFrom returns the session attached by the middleware and whether there was one.
+func SessionFrom(c *gin.Context) (*Session, bool) {
+	if c.Request == nil {
+		return nil, false
 	}
+	return FromContext(c.Request.Context())
 }
 
+// GetSession returns the session attached by the middleware. If there is none it panics,
+// unless the manager has an error handler: then the handler is called, the request is aborted
+// and a detached session that is never persisted is returned.
 func GetSession(c *gin.Context) *Session {
-	session, ok := c.Value("session").(*Session)
-	if !ok {
-		panic("session not found in request context")
+	session, ok := SessionFrom(c)
+	if ok {
+		return session
 	}
 
-	return session
-}
+	var state *requestState
+	if c.Request != nil {
+		state = stateFrom(c.Request.Context())
+	}
+	if state == nil || state.manager.errorHandler == nil {
+		panic(ErrSessionNotFound.Error())
+	}
+	state.manager.errorHandler(c, ErrSessionNotFound)
+	c.Abort()
 
-func (s *inMemorySessionStore) read(id string) *Session {
-	if session, ok := s.sessions.Load(id); ok {
-		return session.(*Session)
+	detached := &Session{
+		createdAt: time.Now(),
+		fresh:     true,
 	}
-	return nil
+	detached.setData(make(map[string]any))
+	detached.touch()
+	return detached
 }
 
-func (s *inMemorySessionStore) write(session *Session) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.sessions.Store(session.id, session)
-
-	return nil
+func (w *sessionContextWriter) Write(b []byte) (int, error) {
+	w.writeCookieIfNecessary(w.ResponseWriter)
+	w.sent = true
+	return w.ResponseWriter.Write(b)
 }
 
-func (s *inMemorySessionStore) destroy(id string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.sessions.Delete(id)
-
-	return nil
+func (w *sessionContextWriter) WriteHeader(code int) {
+	w.writeCookieIfNecessary(w.ResponseWriter)
+	w.ResponseWriter.WriteHeader(code)
 }
 
-func (s *inMemorySessionStore) gc(idleExpiration, absoluteExpiration time.Duration) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (w *sessionContextWriter) WriteString(str string) (int, error) {
+	w.writeCookieIfNecessary(w.ResponseWriter)
+	w.sent = true
+	return w.ResponseWriter.WriteString(str)
+}
 
-	s.sessions.Range(func(key, value any) bool {
-		session := value.(*Session)
-		if time.Since(session.getLastActivity()) > idleExpiration ||
-			time.Since(session.createdAt) > absoluteExpiration {
-			s.sessions.Delete(key)
-			return false
-		}
-		return true
-	})
-	return nil
+func (w *sessionContextWriter) WriteHeaderNow() {
+	w.writeCookieIfNecessary(w.ResponseWriter)
+	w.sent = true
+	w.ResponseWriter.WriteHeaderNow()
 }
 
-func (w *sessionContextWriter) Write(b []byte) (int, error) {
-	writeCookieIfNecessary(w)
-	return w.c.Writer.Write(b)
+func (w *sessionContextWriter) Flush() {
+	w.writeCookieIfNecessary(w.ResponseWriter)
+	w.sent = true
+	w.ResponseWriter.Flush()
 }
 
-func (w *sessionContextWriter) WriteHeader(code int) {
-	writeCookieIfNecessary(w)
-	w.c.Writer.WriteHeader(code)
+func (w *sessionContextWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked()
+	return w.ResponseWriter.Hijack()
 }
 
 func (w *sessionContextWriter) Unwrap() http.ResponseWriter {
-	return w.c.Writer
+	return w.ResponseWriter
 }
 
-func writeCookieIfNecessary(w *sessionContextWriter) {
+// hijacked persists the session when a handler takes over the connection, e.g. for a WebSocket,
+// as the handler may keep the connection open for a long time. The session stays attached to the
+// request for the lifetime of the connection, but no cookie is written anymore. The lock of
+// WithSessionLocking is released, so the connection does not block other requests of the session.
+func (w *cookieWriter) hijacked() {
+	w.done = true
+	err := w.sessionManager.finish(w.state)
+	if err != nil {
+		w.sessionManager.logger.Error("saving session of hijacked connection failed", "error", err)
+	}
+	if w.unlock != nil {
+		w.unlock()
+	}
+}
+
+func (w *cookieWriter) writeCookieIfNecessary(rw http.ResponseWriter) {
 	if w.done {
 		return
 	}
+	if w.sent {
+		// The session was still fresh when the headers were written.
+		w.state.loseCookie()
+		w.done = true
+		return
+	}
+	if cookie := w.state.takeRemember(); cookie != nil {
+		http.SetCookie(rw, cookie)
+	}
 
-	session, ok := w.c.Value("session").(*Session)
+	session, ok := w.state.get()
 	if !ok {
 		panic("session not found in request context")
 	}
+	if session.isFresh() {
+		if w.state.loggedOut() {
+			w.sessionManager.clearToken(rw, w.state.request)
+			w.done = true
+		}
+		return
+	}
+	if !w.sessionManager.needsCookie(session) {
+		w.done = true
+		return
+	}
 
-	name := w.sessionManager.cookieName
-	value := session.id
-	domain := w.domain
-	httpOnly := true
-	path := "/"
-	secure := true
-	maxAge := int(w.sessionManager.idleExpiration / time.Second)
-
-	w.c.SetCookie(name, value, maxAge, path, domain, secure, httpOnly)
+	err := w.sessionManager.writeCookie(rw, w.state.request, session)
+	if err != nil {
+		w.sessionManager.logger.Error("writing session cookie failed", "error", err)
+		return
+	}
+	session.setCookieIssuedAt(w.sessionManager.now())
 	w.done = true
 }
 
-func openFile(name string) (*os.File, error) {
-	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_RDONLY, 0660)
+// needsCookie reports whether the cookie has to be (re)sent. Without a refresh threshold it is sent on every response.
+func (m *SessionManager) needsCookie(session *Session) bool {
+	issuedAt := session.getCookieIssuedAt()
+	if m.refreshThreshold == 0 || issuedAt.IsZero() {
+		return true
+	}
+	return issuedAt.Add(m.idleExpiration).Sub(m.now()) < m.refreshThreshold
+}
+
+// writeCookie sends the token of session in the response to r.
+func (m *SessionManager) writeCookie(w http.ResponseWriter, r *http.Request, session *Session) error {
+	expires := session.createdAt.Add(m.absoluteExpiration)
+	value, err := m.encodeID(session.id, expires)
 	if err != nil {
-		if os.IsNotExist(err) {
-			file, err = os.Create(name)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, err
-		}
+		return err
+	}
+
+	maxAge := m.idleExpiration
+	if m.cookieExpiry {
+		maxAge = max(min(maxAge, expires.Sub(m.now())), time.Second)
+	}
+	m.writeToken(w, r, value, maxAge)
+	return nil
+}
+
+// newCookie returns the session cookie for the response to r, which may be nil.
+func (m *SessionManager) newCookie(r *http.Request, value string, maxAge int) *http.Cookie {
+	domain := m.domain
+	if m.domainFunc != nil && r != nil {
+		domain = m.domainFunc(r)
+	}
+	return &http.Cookie{
+		Name:        m.cookieName,
+		Value:       value,
+		MaxAge:      maxAge,
+		Path:        m.path,
+		Domain:      domain,
+		Secure:      m.secure,
+		HttpOnly:    m.httpOnly,
+		SameSite:    m.sameSite,
+		Partitioned: m.partitioned,
 	}
-	return file, nil
 }
@@ -4,9 +4,12 @@ package session
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -212,11 +215,7 @@ func TestSessionCountOne(t *testing.T) {
 		log.Println("Server Shutdown:", err)
 	}
 	cancel()
-	count := 0
-	store.sessions.Range(func(key, value any) bool {
-		count++
-		return true
-	})
+	count := store.count()
 	assert.Equal(t, 1, count)
 }
 
@@ -273,11 +272,7 @@ func TestSessionCountTwo(t *testing.T) {
 		log.Println("Server Shutdown:", err)
 	}
 	cancel()
-	count := 0
-	store.sessions.Range(func(key, value any) bool {
-		count++
-		return true
-	})
+	count := store.count()
 	assert.Equal(t, 2, count)
 }
 func TestNewSessionManager(t *testing.T) {
@@ -305,7 +300,8 @@ func TestNewSessionManager(t *testing.T) {
 
 func TestValidate(t *testing.T) {
 	sm := NewSessionManager()
-	sess := newSession()
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
 	ok := sm.validate(sess)
 	assert.True(t, ok)
 
@@ -320,15 +316,16 @@ func TestFileStore(t *testing.T) {
 		fileName: "test_session.json",
 		mu:       sync.RWMutex{},
 	}
-	sess := newSession()
-	sess.data.Store("foo", "bar")
-	err := fs.write(sess)
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("foo", "bar")
+	err = fs.write(sess)
 	assert.NoError(t, err)
 	sess2 := fs.read(sess.id)
 	assert.NoError(t, err)
-	data1, ok := sess.data.Load("foo")
+	data1, ok := sess.load("foo")
 	assert.True(t, ok)
-	data2, ok := sess2.data.Load("foo")
+	data2, ok := sess2.load("foo")
 	assert.True(t, ok)
 
 	assert.Equal(t, data1, data2)
@@ -388,3 +385,371 @@ func TestSessionCountTwoWithSessionFile(t *testing.T) {
 	}
 	cancel()
 }
+
+func TestMaxSessionBytes(t *testing.T) {
+	sm := NewSessionManager(WithMaxSessionBytes(32))
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("a", "small")
+	assert.NoError(t, sm.save(sess, nil))
+
+	sess.Put("b", strings.Repeat("x", 64))
+	err = sm.save(sess, nil)
+	assert.ErrorIs(t, err, ErrSessionTooLarge)
+	// The in-memory store shares the session, so the rejected changes are rolled back.
+	assert.Nil(t, sm.read(sess.id).Get("b"))
+	assert.Equal(t, []string{"a"}, sm.read(sess.id).Keys())
+	sess.Put("a", strings.Repeat("x", 64))
+	assert.ErrorIs(t, sm.save(sess, nil), ErrSessionTooLarge)
+	assert.Equal(t, "small", sm.read(sess.id).Get("a"))
+	assert.Equal(t, uint64(1), sm.read(sess.id).Version())
+
+	sm = NewSessionManager(WithStore(NewFileStore(filepath.Join(t.TempDir(), "sessions.json"))), WithMaxSessionBytes(32))
+	sess, err = newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("a", "small")
+	assert.NoError(t, sm.save(sess, nil))
+	sess.Put("b", strings.Repeat("x", 64))
+	assert.ErrorIs(t, sm.save(sess, nil), ErrSessionTooLarge)
+	assert.Nil(t, sm.read(sess.id).Get("b"))
+	assert.Equal(t, "small", sm.read(sess.id).Get("a"))
+}
+
+func TestMaxSessionBytesEvictOldest(t *testing.T) {
+	sm := NewSessionManager(WithMaxSessionBytes(32), WithQuotaPolicy(QuotaEvictOldest))
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("a", "first")
+	sess.Put("b", "second")
+	sess.Put("c", "third")
+	sess.Put("a", "rewritten")
+
+	assert.NoError(t, sm.save(sess, nil))
+	assert.Nil(t, sess.Get("b"))
+	assert.Equal(t, "rewritten", sess.Get("a"))
+
+	size, err := sess.size()
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, size, 32)
+}
+
+func TestSessionConcurrentAccess(t *testing.T) {
+	sm := NewSessionManager(WithMaxSessionBytes(1024), WithQuotaPolicy(QuotaEvictOldest))
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i%3)
+			for j := 0; j < 200; j++ {
+				sess.Put(key, j)
+				sess.Get(key)
+				_, _ = GetGenericValue[int](sess, key)
+				if j%10 == 0 {
+					sess.Delete(key)
+				}
+				assert.NoError(t, sm.save(sess, nil))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestHandleSavesAfterHandler(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	router := gin.New()
+	router.Use(sm.Handle())
+
+	sessionID := ""
+	router.GET("/values", func(c *gin.Context) {
+		c.String(http.StatusOK, "done")
+		sess := GetSession(c)
+		sessionID = sess.id
+		sess.Put("written", "after body")
+	})
+	router.GET("/start", func(c *gin.Context) {
+		GetSession(c).Put("written", "before body")
+	})
+
+	// The cookie of a new session cannot be sent after the body, so it is not saved.
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/values", nil))
+	assert.Empty(t, rw.Result().Cookies())
+	assert.Nil(t, store.read(sessionID))
+
+	// Existing sessions are saved after the handler.
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/start", nil))
+	cookie := rw.Result().Cookies()[0]
+	rw = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/values", nil)
+	req.AddCookie(cookie)
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, cookie.Value, sessionID)
+	assert.Equal(t, "after body", store.read(sessionID).Get("written"))
+}
+
+func TestSessionContextWriterWritesCookieFirst(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+	}{
+		{"Write", func(c *gin.Context) { c.Writer.Write([]byte("done")) }},
+		{"WriteString", func(c *gin.Context) { c.Writer.WriteString("done") }},
+		{"WriteHeaderNow", func(c *gin.Context) { c.Writer.WriteHeaderNow() }},
+		{"Flush", func(c *gin.Context) { c.Writer.Flush() }},
+		{"Status", func(c *gin.Context) { c.Status(http.StatusNoContent) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewSessionManager()
+			router := gin.New()
+			router.Use(sm.Handle())
+			router.GET("/", func(c *gin.Context) {
+				GetSession(c).Put("key", "value")
+				tt.handler(c)
+			})
+
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Len(t, rw.Result().Cookies(), 1)
+		})
+	}
+}
+
+func TestLazySessionCreation(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/read", func(c *gin.Context) {
+		assert.Nil(t, GetSession(c).Get("key"))
+		c.String(http.StatusOK, "done")
+	})
+	router.GET("/write", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+		c.String(http.StatusOK, "done")
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/read", nil))
+	assert.Empty(t, rw.Result().Cookies())
+	count := store.count()
+	assert.Equal(t, 0, count)
+
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/write", nil))
+	cookies := rw.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.NotNil(t, store.read(cookies[0].Value))
+}
+
+func TestSkipper(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(
+		WithStore(store),
+		WithSkipper(func(c *gin.Context) bool {
+			return c.Request.URL.Path == "/healthz"
+		}),
+	)
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/healthz", func(c *gin.Context) {
+		_, ok := c.Get("session")
+		assert.False(t, ok)
+		c.String(http.StatusOK, "ok")
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Empty(t, rw.Header().Get("Vary"))
+}
+
+func TestErrorHandler(t *testing.T) {
+	var handled []error
+	sm := NewSessionManager(
+		WithSkipper(func(c *gin.Context) bool { return true }),
+		WithErrorHandler(func(c *gin.Context, err error) {
+			handled = append(handled, err)
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+		}),
+	)
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		sess.Put("key", "value")
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Equal(t, []error{ErrSessionNotFound}, handled)
+}
+
+func TestSessionFrom(t *testing.T) {
+	sm := NewSessionManager(WithSkipper(func(c *gin.Context) bool {
+		return c.Request.URL.Path == "/skipped"
+	}))
+	router := gin.New()
+	router.Use(sm.Handle())
+	found := map[string]bool{}
+	handler := func(c *gin.Context) {
+		_, ok := SessionFrom(c)
+		found[c.Request.URL.Path] = ok
+	}
+	router.GET("/skipped", handler)
+	router.GET("/session", handler)
+
+	for _, path := range []string{"/skipped", "/session"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	assert.Equal(t, map[string]bool{"/skipped": false, "/session": true}, found)
+}
+
+type closingStore struct {
+	*inMemorySessionStore
+	closed int
+}
+
+func (s *closingStore) Close() error {
+	s.closed++
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	store := &closingStore{inMemorySessionStore: NewInMemorySessionStore()}
+	sm := NewSessionManager(WithStore(store), WithAbsoluteExpiration(time.Millisecond))
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+	assert.NoError(t, sm.save(sess, nil))
+	time.Sleep(2 * time.Millisecond)
+
+	assert.NoError(t, sm.Close())
+	assert.NoError(t, sm.Close())
+	assert.Nil(t, store.read(sess.id))
+	assert.Equal(t, 1, store.closed)
+
+	select {
+	case <-sm.gcDone:
+	default:
+		t.Fatal("gc goroutine still running after Close")
+	}
+}
+
+func TestCookieRefreshThreshold(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(
+		WithStore(store),
+		WithIdleExpiration(10*time.Minute),
+		WithCookieRefreshThreshold(5*time.Minute),
+	)
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+	do := func(cookie *http.Cookie) []*http.Cookie {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		return rw.Result().Cookies()
+	}
+
+	cookies := do(nil)
+	assert.Len(t, cookies, 1)
+	assert.Empty(t, do(cookies[0]))
+
+	store.read(cookies[0].Value).setCookieIssuedAt(time.Now().Add(-6 * time.Minute))
+	assert.Len(t, do(cookies[0]), 1)
+}
+
+func TestSameSite(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want http.SameSite
+	}{
+		{"default", nil, http.SameSiteLaxMode},
+		{"strict", []Option{WithSameSite(http.SameSiteStrictMode)}, http.SameSiteStrictMode},
+		{"none", []Option{WithSameSite(http.SameSiteNoneMode)}, http.SameSiteNoneMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, issueCookie(t, tt.opts...).SameSite)
+		})
+	}
+}
+
+// issueCookie returns the cookie a manager configured with opts sends for a new session.
+func issueCookie(t *testing.T, opts ...Option) *http.Cookie {
+	sm := NewSessionManager(opts...)
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rw.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected one cookie, got %d", len(cookies))
+	}
+	return cookies[0]
+}
+
+func TestCookiePath(t *testing.T) {
+	assert.Equal(t, "/", issueCookie(t).Path)
+	assert.Equal(t, "/app", issueCookie(t, WithCookiePath("/app")).Path)
+	assert.Panics(t, func() { NewSessionManager(WithCookiePath("app")) })
+}
+
+func TestCookieSecureAndHTTPOnly(t *testing.T) {
+	cookie := issueCookie(t)
+	assert.True(t, cookie.Secure)
+	assert.True(t, cookie.HttpOnly)
+
+	cookie = issueCookie(t, WithCookieSecure(false), WithCookieHTTPOnly(false))
+	assert.False(t, cookie.Secure)
+	assert.False(t, cookie.HttpOnly)
+}
+
+func TestCopyOnWriteData(t *testing.T) {
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+	before := sess.snapshot()
+
+	sess.Put("key", "changed")
+	sess.Put("other", 1)
+	assert.Equal(t, map[string]any{"key": "value"}, before)
+	assert.Equal(t, "changed", sess.Get("key"))
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if i == 0 {
+					sess.Put("counter", i)
+				}
+				sess.Get("key")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, "changed", sess.Get("key"))
+}
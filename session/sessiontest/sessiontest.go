@@ -0,0 +1,98 @@
+// Package sessiontest helps testing gin handlers that use sessions.
+//
+// WithSession attaches a session with given values to a gin test context, so a handler can be
+// called directly. Client sends requests through a router in memory and keeps the cookies it
+// sets like a browser, so a test can log in and use the session on the next request. Clock lets
+// sessions expire without waiting, see session.WithClock.
+package sessiontest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+)
+
+// NewTestManager returns a manager with an in-memory store that is closed when the test ends.
+func NewTestManager(t testing.TB, opts ...session.Option) *session.SessionManager {
+	t.Helper()
+	sm := session.NewSessionManager(opts...)
+	t.Cleanup(func() { sm.Close() })
+	return sm
+}
+
+// manager creates the sessions of WithSession, they are never saved.
+var manager = sync.OnceValue(func() *session.SessionManager { return session.NewSessionManager() })
+
+// WithSession attaches a new session holding data to c, e.g. a context of gin.CreateTestContext,
+// and returns it. A GET request to / is set if c has none. The session is not saved.
+func WithSession(c *gin.Context, data map[string]any) *session.Session {
+	if c.Request == nil {
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	}
+	sm := manager()
+	sess, err := sm.Create(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	for key, value := range data {
+		sess.Put(key, value)
+	}
+	sm.Attach(c, sess)
+	return sess
+}
+
+// baseURL is the origin of the requests of Client. It uses https, so secure cookies are kept.
+var baseURL = &url.URL{Scheme: "https", Host: "example.com"}
+
+// Client sends requests to a handler without a server and keeps the cookies of the responses.
+type Client struct {
+	handler http.Handler
+	jar     *cookiejar.Jar
+}
+
+// NewClient returns a client sending requests to handler, usually a *gin.Engine.
+func NewClient(handler http.Handler) *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{handler: handler, jar: jar}
+}
+
+// Do sends req with the stored cookies and stores the cookies of the response.
+// Requests without host, e.g. of httptest.NewRequest, are sent to https://example.com.
+func (c *Client) Do(req *http.Request) *httptest.ResponseRecorder {
+	if req.URL.Host == "" {
+		req.URL.Scheme, req.URL.Host = baseURL.Scheme, baseURL.Host
+		req.Host = baseURL.Host
+	}
+	for _, cookie := range c.jar.Cookies(req.URL) {
+		req.AddCookie(cookie)
+	}
+	rw := httptest.NewRecorder()
+	c.handler.ServeHTTP(rw, req)
+	c.jar.SetCookies(req.URL, rw.Result().Cookies())
+	return rw
+}
+
+// Get sends a GET request for path.
+func (c *Client) Get(path string) *httptest.ResponseRecorder {
+	return c.Do(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+// Post sends a POST request with body of the given content type to path.
+func (c *Client) Post(path, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// Cookies returns the cookies the client sends with the next request.
+func (c *Client) Cookies() []*http.Cookie {
+	return c.jar.Cookies(baseURL)
+}
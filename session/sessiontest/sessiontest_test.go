@@ -0,0 +1,70 @@
+package sessiontest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+)
+
+func TestWithSession(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	sess := WithSession(c, map[string]any{"user": "alice"})
+
+	func(c *gin.Context) {
+		current := session.GetSession(c)
+		assert.Equal(t, "alice", current.Get("user"))
+		current.Put("theme", "dark")
+	}(c)
+
+	assert.Equal(t, "dark", sess.Get("theme"))
+	assert.NotEmpty(t, sess.ID())
+}
+
+func TestClient(t *testing.T) {
+	sm := NewTestManager(t)
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.POST("/login", func(c *gin.Context) {
+		assert.NoError(t, sm.Login(c, c.PostForm("user")))
+	})
+	router.GET("/me", func(c *gin.Context) {
+		c.String(http.StatusOK, "%v", session.GetSession(c).Get(session.UserIDKey))
+	})
+	router.POST("/logout", func(c *gin.Context) {
+		assert.NoError(t, sm.Logout(c))
+	})
+
+	client := NewClient(router)
+	client.Post("/login", "application/x-www-form-urlencoded", strings.NewReader("user=alice"))
+	assert.Len(t, client.Cookies(), 1)
+	assert.Equal(t, "alice", client.Get("/me").Body.String())
+
+	client.Post("/logout", "", nil)
+	assert.Empty(t, client.Cookies())
+	assert.Equal(t, "<nil>", client.Get("/me").Body.String())
+}
+
+func TestClock(t *testing.T) {
+	clock := NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm := NewTestManager(t, session.WithClock(clock), session.WithIdleExpiration(time.Minute))
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		count, _ := session.GetGenericValue[float64](session.GetSession(c), "count")
+		session.GetSession(c).Put("count", count+1)
+		c.String(http.StatusOK, "%v", count+1)
+	})
+
+	client := NewClient(router)
+	assert.Equal(t, "1", client.Get("/").Body.String())
+	clock.Advance(59 * time.Second)
+	assert.Equal(t, "2", client.Get("/").Body.String())
+	clock.Advance(61 * time.Second)
+	assert.Equal(t, "1", client.Get("/").Body.String())
+}
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// WithSigningKey signs the cookie value with HMAC-SHA256, so it becomes "value.signature".
+// Cookies with a missing or wrong signature are rejected before the store is asked for the session.
+func WithSigningKey(key []byte) Option {
+	return WithSigningKeys(key)
+}
+
+// WithSigningKeys is WithSigningKey with key rotation: cookies are signed with current,
+// while signatures of the previous keys are still accepted.
+func WithSigningKeys(current []byte, previous ...[]byte) Option {
+	return func(s *SessionManager) {
+		keys := append([][]byte{current}, previous...)
+		for _, key := range keys {
+			if len(key) == 0 {
+				panic(errors.New("signing key cannot be empty"))
+			}
+		}
+		s.signingKeys = keys
+	}
+}
+
+func sign(key []byte, value string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(value))
+	return value + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// verify returns the value signed by sign and whether it was signed by one of keys.
+func verify(keys [][]byte, signed string) (string, bool) {
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", false
+	}
+	value := signed[:i]
+	for _, key := range keys {
+		if hmac.Equal([]byte(signed), []byte(sign(key, value))) {
+			return value, true
+		}
+	}
+	return "", false
+}
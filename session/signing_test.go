@@ -0,0 +1,78 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigningKey(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store), WithSigningKey([]byte("secret")))
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		count, _ := GetGenericValue[int](sess, "count")
+		sess.Put("count", count+1)
+		c.String(http.StatusOK, "%d", count+1)
+	})
+	do := func(value string) (string, *http.Cookie) {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if value != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: value})
+		}
+		router.ServeHTTP(rw, req)
+		return rw.Body.String(), rw.Result().Cookies()[0]
+	}
+
+	_, cookie := do("")
+	id, _, ok := strings.Cut(cookie.Value, ".")
+	assert.True(t, ok)
+	assert.NotNil(t, store.read(id))
+
+	body, _ := do(cookie.Value)
+	assert.Equal(t, "2", body)
+
+	for _, forged := range []string{id, id + ".", id + ".AAAA", "other." + strings.Split(cookie.Value, ".")[1]} {
+		body, _ = do(forged)
+		assert.Equal(t, "1", body, forged)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldSigning, newSigning := []byte("old secret"), []byte("new secret")
+	oldEncryption, newEncryption := []byte("0123456789abcdef"), []byte("fedcba9876543210")
+	store := NewInMemorySessionStore()
+
+	old := NewSessionManager(WithStore(store), WithSigningKeys(oldSigning), WithEncryptionKeys(oldEncryption))
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	value, err := old.encodeID(sess.id, time.Time{})
+	assert.NoError(t, err)
+
+	rotated := NewSessionManager(
+		WithStore(store),
+		WithSigningKeys(newSigning, oldSigning),
+		WithEncryptionKeys(newEncryption, oldEncryption),
+	)
+	id, ok := rotated.decodeID(value)
+	assert.True(t, ok)
+	assert.Equal(t, sess.id, id)
+
+	value, err = rotated.encodeID(sess.id, time.Time{})
+	assert.NoError(t, err)
+	_, ok = old.decodeID(value)
+	assert.False(t, ok)
+
+	dropped := NewSessionManager(WithStore(store), WithSigningKeys(newSigning), WithEncryptionKeys(newEncryption))
+	id, ok = dropped.decodeID(value)
+	assert.True(t, ok)
+	assert.Equal(t, sess.id, id)
+}
@@ -0,0 +1,53 @@
+package session
+
+import "sync"
+
+// WithSingleflightReads collapses concurrent reads of the same session id into one store read,
+// e.g. for the burst of requests a single page application sends on load. The requests then share
+// the session object, as they do with the in-memory store. Worth it for remote stores only.
+func WithSingleflightReads(enabled bool) Option {
+	return func(s *SessionManager) {
+		if enabled {
+			s.reads = &readGroup{}
+		} else {
+			s.reads = nil
+		}
+	}
+}
+
+// readGroup runs one read per key at a time and hands its result to all concurrent callers.
+type readGroup struct {
+	mu    sync.Mutex
+	calls map[string]*readCall
+}
+
+type readCall struct {
+	wg      sync.WaitGroup
+	session *Session
+	err     error
+}
+
+func (g *readGroup) do(key string, read func() (*Session, error)) (*Session, error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.session, call.err
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*readCall)
+	}
+	call := &readCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+	call.session, call.err = read()
+	return call.session, call.err
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowStore counts reads, which take a while like those of a remote store.
+type slowStore struct {
+	*inMemorySessionStore
+	reads atomic.Int32
+}
+
+func (s *slowStore) read(id string) *Session {
+	s.reads.Add(1)
+	time.Sleep(50 * time.Millisecond)
+	return s.inMemorySessionStore.read(id)
+}
+
+func TestSingleflightReads(t *testing.T) {
+	store := &slowStore{inMemorySessionStore: NewInMemorySessionStore()}
+	sm := NewSessionManager(WithStore(store), WithSingleflightReads(true))
+	t.Cleanup(func() { sm.Close() })
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("key", "value")
+	assert.NoError(t, sm.save(sess, nil))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Same(t, sess, sm.read(sess.id))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), store.reads.Load())
+
+	assert.Same(t, sess, sm.read(sess.id))
+	assert.Equal(t, int32(2), store.reads.Load())
+}
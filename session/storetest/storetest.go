@@ -0,0 +1,260 @@
+// Package storetest checks that a session store behaves as the session manager expects.
+//
+// The store is driven through a SessionManager, so the suite covers what handlers observe: values
+// written are read back, destroyed and expired sessions are gone, the garbage collection removes
+// exactly the expired sessions and concurrent use neither fails nor loses writes. Time is
+// simulated with session.WithClock, so the expiry tests do not sleep.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+	"github.com/zetr0nix/gin-memory-sessions-go/session/sessiontest"
+)
+
+const (
+	idleExpiration     = 10 * time.Minute
+	absoluteExpiration = time.Hour
+)
+
+// RunConformanceTests runs the conformance suite as subtests of t. newStore is called for every
+// subtest and has to return an empty store.
+func RunConformanceTests(t *testing.T, newStore func() session.SessionStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, h *harness)
+	}{
+		{"WriteRead", testWriteRead},
+		{"ReadMissing", testReadMissing},
+		{"Update", testUpdate},
+		{"Destroy", testDestroy},
+		{"ConcurrentChanges", testConcurrentChanges},
+		{"IdleExpiration", testIdleExpiration},
+		{"AbsoluteExpiration", testAbsoluteExpiration},
+		{"GC", testGC},
+		{"Concurrency", testConcurrency},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newHarness(t, newStore()))
+		})
+	}
+}
+
+// harness is a manager using the store under test with a simulated clock.
+type harness struct {
+	sm    *session.SessionManager
+	clock *sessiontest.Clock
+}
+
+func newHarness(t *testing.T, store session.SessionStore) *harness {
+	h := &harness{clock: sessiontest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+	h.sm = session.NewSessionManager(
+		session.WithStore(store),
+		session.WithClock(h.clock),
+		session.WithIdleExpiration(idleExpiration),
+		session.WithAbsoluteExpiration(absoluteExpiration),
+	)
+	t.Cleanup(func() { h.sm.Close() })
+	return h
+}
+
+// create saves a new session holding values and returns its id.
+func (h *harness) create(t *testing.T, values map[string]any) string {
+	t.Helper()
+	sess, err := h.sm.Create(context.Background())
+	require.NoError(t, err)
+	for key, value := range values {
+		sess.Put(key, value)
+	}
+	require.NoError(t, h.sm.Commit(context.Background(), sess))
+	return sess.ID()
+}
+
+// touch loads and saves the session of id, as a request not changing it does.
+func (h *harness) touch(t *testing.T, id string) {
+	t.Helper()
+	sess, err := h.sm.Load(context.Background(), id)
+	require.NoError(t, err)
+	require.NoError(t, h.sm.Commit(context.Background(), sess))
+}
+
+func (h *harness) exists(id string) bool {
+	_, err := h.sm.Load(context.Background(), id)
+	return err == nil
+}
+
+func testWriteRead(t *testing.T, h *harness) {
+	id := h.create(t, map[string]any{"name": "alice", "count": float64(3), "admin": true})
+
+	sess, err := h.sm.Load(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, id, sess.ID())
+	assert.Equal(t, "alice", sess.Get("name"))
+	assert.Equal(t, float64(3), sess.Get("count"))
+	assert.Equal(t, true, sess.Get("admin"))
+	assert.Equal(t, uint64(1), sess.Version())
+	assert.ElementsMatch(t, []string{"name", "count", "admin"}, sess.Keys())
+}
+
+func testReadMissing(t *testing.T, h *harness) {
+	_, err := h.sm.Load(context.Background(), "missing")
+	assert.ErrorIs(t, err, session.ErrUnknownSession)
+}
+
+func testUpdate(t *testing.T, h *harness) {
+	id := h.create(t, map[string]any{"a": "initial", "b": "initial"})
+
+	sess, err := h.sm.Load(context.Background(), id)
+	require.NoError(t, err)
+	sess.Put("a", "changed")
+	sess.Delete("b")
+	sess.Put("c", "added")
+	require.NoError(t, h.sm.Commit(context.Background(), sess))
+
+	sess, err = h.sm.Load(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "changed", sess.Get("a"))
+	assert.Nil(t, sess.Get("b"))
+	assert.Equal(t, "added", sess.Get("c"))
+	assert.Equal(t, uint64(2), sess.Version())
+}
+
+func testDestroy(t *testing.T, h *harness) {
+	id := h.create(t, map[string]any{"a": "value"})
+	other := h.create(t, map[string]any{"a": "value"})
+
+	sess, err := h.sm.Load(context.Background(), id)
+	require.NoError(t, err)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	h.sm.Attach(c, sess)
+	require.NoError(t, h.sm.Destroy(c))
+
+	assert.False(t, h.exists(id))
+	assert.True(t, h.exists(other))
+}
+
+// testConcurrentChanges saves two copies of a session loaded at the same time. The store either
+// reports session.ErrVersionConflict for the second, which the manager merges, or shares the session.
+func testConcurrentChanges(t *testing.T, h *harness) {
+	id := h.create(t, map[string]any{"a": "initial", "b": "initial"})
+
+	first, err := h.sm.Load(context.Background(), id)
+	require.NoError(t, err)
+	second, err := h.sm.Load(context.Background(), id)
+	require.NoError(t, err)
+	first.Put("a", "first")
+	second.Put("b", "second")
+	require.NoError(t, h.sm.Commit(context.Background(), first))
+	require.NoError(t, h.sm.Commit(context.Background(), second))
+
+	sess, err := h.sm.Load(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "first", sess.Get("a"))
+	assert.Equal(t, "second", sess.Get("b"))
+}
+
+func testIdleExpiration(t *testing.T, h *harness) {
+	id := h.create(t, map[string]any{"a": "value"})
+
+	h.clock.Advance(idleExpiration)
+	assert.True(t, h.exists(id), "idle for exactly the idle expiration")
+	h.touch(t, id)
+
+	h.clock.Advance(idleExpiration + time.Nanosecond)
+	assert.False(t, h.exists(id))
+}
+
+func testAbsoluteExpiration(t *testing.T, h *harness) {
+	id := h.create(t, map[string]any{"a": "value"})
+
+	// The last touch happens exactly the absolute expiration after the creation
+	for range absoluteExpiration / idleExpiration {
+		h.clock.Advance(idleExpiration)
+		h.touch(t, id)
+	}
+	h.clock.Advance(time.Nanosecond)
+	assert.False(t, h.exists(id))
+}
+
+func testGC(t *testing.T, h *harness) {
+	var expired []string
+	for range 3 {
+		expired = append(expired, h.create(t, map[string]any{"a": "value"}))
+	}
+	h.clock.Advance(idleExpiration / 2)
+	active := h.create(t, map[string]any{"a": "value"})
+
+	stats, err := h.sm.RunGC(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Deleted)
+
+	h.clock.Advance(idleExpiration/2 + time.Nanosecond)
+	stats, err = h.sm.RunGC(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, len(expired), stats.Deleted)
+	assert.True(t, h.exists(active))
+
+	stats, err = h.sm.RunGC(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Deleted, "expired sessions are reported once")
+}
+
+func testConcurrency(t *testing.T, h *harness) {
+	const workers, rounds = 8, 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers+1)
+	ids := make([]string, workers)
+	for w := range workers {
+		wg.Go(func() {
+			sess, err := h.sm.Create(context.Background())
+			if err == nil {
+				err = h.sm.Commit(context.Background(), sess)
+			}
+			for i := 0; err == nil && i < rounds; i++ {
+				sess, err = h.sm.Load(context.Background(), sess.ID())
+				if err != nil {
+					break
+				}
+				sess.Put(fmt.Sprint("key", i), float64(i))
+				err = h.sm.Commit(context.Background(), sess)
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			ids[w] = sess.ID()
+		})
+	}
+	wg.Go(func() {
+		for range rounds {
+			if _, err := h.sm.RunGC(context.Background()); err != nil {
+				errs <- err
+				return
+			}
+		}
+	})
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	for _, id := range ids {
+		sess, err := h.sm.Load(context.Background(), id)
+		require.NoError(t, err)
+		assert.Len(t, sess.Keys(), rounds)
+	}
+}
@@ -0,0 +1,30 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zetr0nix/gin-memory-sessions-go/session"
+)
+
+func TestInMemorySessionStore(t *testing.T) {
+	RunConformanceTests(t, func() session.SessionStore { return session.NewInMemorySessionStore() })
+}
+
+func TestEncryptedStore(t *testing.T) {
+	RunConformanceTests(t, func() session.SessionStore {
+		store, err := session.NewEncryptedStoreFromKeys(session.NewInMemorySessionStore(), session.StaticKeys([]byte("0123456789abcdef")))
+		require.NoError(t, err)
+		return store
+	})
+}
+
+func TestMigratingStore(t *testing.T) {
+	RunConformanceTests(t, func() session.SessionStore {
+		return session.NewMigratingStore(session.NewInMemorySessionStore(), session.NewInMemorySessionStore())
+	})
+}
+
+func TestMockStore(t *testing.T) {
+	RunConformanceTests(t, func() session.SessionStore { return session.NewMockStore() })
+}
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenKeyPrefix prefixes the session keys of one-time tokens.
+const tokenKeyPrefix = "token."
+
+// IssueToken creates a single use token for purpose that is valid for ttl, e.g. for download links
+// or to protect a form against replay. Issuing a new token for a purpose replaces the previous one.
+func (s *Session) IssueToken(purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	s.Put(tokenKeyPrefix+purpose, token+"."+strconv.FormatInt(s.now().Add(ttl).UnixNano(), 10))
+	return token, nil
+}
+
+// ConsumeToken reports whether token is the unexpired token issued for purpose and invalidates it.
+// Of concurrent calls on the same session only one succeeds. Concurrent requests only share the
+// session if the store shares sessions or WithSessionLocking is enabled, otherwise each request
+// consumes its own copy and the token may be accepted more than once.
+func (s *Session) ConsumeToken(purpose, token string) bool {
+	key := tokenKeyPrefix + purpose
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, _ := s.snapshot()[key].(string)
+	value, expires, ok := strings.Cut(stored, ".")
+	if !ok {
+		return false
+	}
+	nanos, err := strconv.ParseInt(expires, 10, 64)
+	expired := err != nil || s.now().UnixNano() > nanos
+	valid := !expired && subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+	if valid || expired {
+		s.deleteLocked(key)
+	}
+	return valid
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOneTimeToken(t *testing.T) {
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+
+	token, err := sess.IssueToken("download", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, sess.isFresh())
+	assert.False(t, sess.ConsumeToken("verify", token))
+	assert.False(t, sess.ConsumeToken("download", "guessed"))
+
+	var consumed atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sess.ConsumeToken("download", token) {
+				consumed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), consumed.Load())
+
+	token, err = sess.IssueToken("download", -time.Second)
+	assert.NoError(t, err)
+	assert.False(t, sess.ConsumeToken("download", token))
+	assert.Nil(t, sess.Get(tokenKeyPrefix+"download"))
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Tracer starts spans for the store operations of the manager, so slow persistence shows up in
+// distributed traces. An OpenTelemetry tracer is adapted in a few lines:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) StartSpan(ctx context.Context, name string) (context.Context, session.Span) {
+//		ctx, span := t.Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	}
+//
+// where otelSpan maps SetAttribute to span.SetAttributes, RecordError to span.RecordError and End to span.End.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a started span of a Tracer.
+type Span interface {
+	// SetAttribute sets an attribute, value is a string, bool or int.
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// WithTracer traces reads, writes and destroys of sessions and garbage collections as spans named
+// "session.read", "session.write", "session.destroy" and "session.gc". Spans carry the store type as
+// "session.store" and, depending on the operation, "session.hit", "session.size" or "session.deleted".
+func WithTracer(tracer Tracer) Option {
+	return func(s *SessionManager) {
+		s.tracer = tracer
+	}
+}
+
+// wrapper is implemented by stores that add behavior to another store, e.g. NewEncryptedStore.
+type wrapper interface {
+	unwrap() SessionStore
+}
+
+// innermost returns the store at the bottom of the wrappers of store, which names the store in spans.
+func innermost(store SessionStore) SessionStore {
+	for {
+		w, ok := store.(wrapper)
+		if !ok {
+			return store
+		}
+		store = w.unwrap()
+	}
+}
+
+// storeSpan is a span of a store operation, or a no-op without tracer.
+type storeSpan struct {
+	span Span
+}
+
+// startSpan starts a span for a store operation.
+func (m *SessionManager) startSpan(ctx context.Context, name string) storeSpan {
+	if m.tracer == nil {
+		return storeSpan{}
+	}
+	_, span := m.tracer.StartSpan(ctx, name)
+	span.SetAttribute("session.store", fmt.Sprintf("%T", innermost(m.store)))
+	return storeSpan{span: span}
+}
+
+func (s storeSpan) set(key string, value any) {
+	if s.span != nil {
+		s.span.SetAttribute(key, value)
+	}
+}
+
+// end records err, if any, and ends the span.
+func (s storeSpan) end(err error) {
+	if s.span == nil {
+		return
+	}
+	if err != nil {
+		s.span.RecordError(err)
+	}
+	s.span.End()
+}
+
+// requestContext returns the context of r, which may be nil.
+func requestContext(r *http.Request) context.Context {
+	if r == nil {
+		return context.Background()
+	}
+	return r.Context()
+}
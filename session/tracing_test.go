@@ -0,0 +1,82 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedSpan struct {
+	name       string
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value any) { s.attributes[key] = value }
+func (s *recordedSpan) RecordError(err error)              { s.err = err }
+func (s *recordedSpan) End()                               { s.ended = true }
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	span := &recordedSpan{name: name, attributes: make(map[string]any)}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	sm := NewSessionManager(WithTracer(tracer))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		GetSession(c).Put("key", "value")
+	})
+	router.GET("/destroy", func(c *gin.Context) {
+		assert.NoError(t, sm.Destroy(c))
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookie := rw.Result().Cookies()[0]
+	for _, path := range []string{"/", "/destroy"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(cookie)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	_, err := sm.RunGC(context.Background())
+	assert.NoError(t, err)
+
+	var names []string
+	for _, span := range tracer.spans {
+		names = append(names, span.name)
+		assert.True(t, span.ended)
+		assert.Equal(t, "*session.inMemorySessionStore", span.attributes["session.store"])
+	}
+	assert.Equal(t, []string{"session.write", "session.read", "session.write", "session.read", "session.destroy", "session.gc"}, names)
+	assert.Equal(t, 15, tracer.spans[0].attributes["session.size"])
+	assert.Equal(t, true, tracer.spans[1].attributes["session.hit"])
+	assert.Equal(t, 0, tracer.spans[5].attributes["session.deleted"])
+}
+
+func TestTracingNamesInnermostStore(t *testing.T) {
+	tracer := &recordingTracer{}
+	store, err := NewEncryptedStoreFromKeys(NewInMemorySessionStore(), StaticKeys([]byte("0123456789abcdef")))
+	assert.NoError(t, err)
+	sm := NewSessionManager(WithStore(store), WithTracer(tracer), WithMetrics(NewMetrics()), WithStoreRetry(2, time.Millisecond, nil))
+	t.Cleanup(func() { sm.Close() })
+
+	_, err = sm.RunGC(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, tracer.spans, 1)
+	assert.Equal(t, "*session.inMemorySessionStore", tracer.spans[0].attributes["session.store"])
+}
@@ -0,0 +1,197 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Transport carries the session token, the encoded session id, between client and server.
+// By default it is sent as cookie.
+type Transport interface {
+	// ReadToken returns the token sent with r, if there is one.
+	ReadToken(r *http.Request) (string, bool)
+	// WriteToken sends token to the client, which should keep it for maxAge.
+	WriteToken(w http.ResponseWriter, token string, maxAge time.Duration)
+	// ClearToken tells the client to discard its token.
+	ClearToken(w http.ResponseWriter)
+}
+
+// HeaderTransport sends the token in a request header and echoes it in the response header
+// of the same name, for SPAs and mobile clients that cannot use cookies.
+type HeaderTransport struct {
+	// Name of the header, e.g. X-Session-Token.
+	Name string
+}
+
+func (t HeaderTransport) ReadToken(r *http.Request) (string, bool) {
+	token := r.Header.Get(t.Name)
+	return token, token != ""
+}
+
+func (t HeaderTransport) WriteToken(w http.ResponseWriter, token string, maxAge time.Duration) {
+	w.Header().Set(t.Name, token)
+}
+
+// ClearToken responds with an empty header.
+func (t HeaderTransport) ClearToken(w http.ResponseWriter) {
+	w.Header()[http.CanonicalHeaderKey(t.Name)] = []string{""}
+}
+
+// BearerTransport reads the token from an "Authorization: Bearer <token>" header, for JSON APIs.
+// Clients learn their token from ResponseHeader or from a response body filled with SessionManager.Token.
+type BearerTransport struct {
+	// ResponseHeader names the header carrying the token in responses. It is not sent if empty.
+	ResponseHeader string
+}
+
+func (t BearerTransport) ReadToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func (t BearerTransport) WriteToken(w http.ResponseWriter, token string, maxAge time.Duration) {
+	if t.ResponseHeader != "" {
+		w.Header().Set(t.ResponseHeader, token)
+	}
+}
+
+// ClearToken responds with an empty ResponseHeader.
+func (t BearerTransport) ClearToken(w http.ResponseWriter) {
+	if t.ResponseHeader != "" {
+		w.Header()[http.CanonicalHeaderKey(t.ResponseHeader)] = []string{""}
+	}
+}
+
+// CookieTransport sends the token as the cookie configured by the cookie options. It is the default transport.
+// A zero CookieTransport passed to WithTokenTransport is bound to the manager it configures.
+type CookieTransport struct {
+	m *SessionManager
+}
+
+func (t CookieTransport) ReadToken(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(t.m.cookieName)
+	if err != nil {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+func (t CookieTransport) WriteToken(w http.ResponseWriter, token string, maxAge time.Duration) {
+	t.write(w, nil, token, int(maxAge/time.Second))
+}
+
+func (t CookieTransport) ClearToken(w http.ResponseWriter) {
+	t.write(w, nil, "", -1)
+}
+
+// write sets the cookie for the response to r, whose domain may depend on r.
+func (t CookieTransport) write(w http.ResponseWriter, r *http.Request, token string, maxAge int) {
+	http.SetCookie(w, t.m.newCookie(r, token, maxAge))
+}
+
+// QueryTransport reads the token from a query parameter, e.g. for download links or WebSocket handshakes.
+// Tokens are never written to it, so it is meant as fallback after other transports.
+// Query parameters end up in logs and browser histories, prefer the other transports where possible.
+type QueryTransport struct {
+	// Name of the query parameter.
+	Name string
+}
+
+func (t QueryTransport) ReadToken(r *http.Request) (string, bool) {
+	token := r.URL.Query().Get(t.Name)
+	return token, token != ""
+}
+
+func (t QueryTransport) WriteToken(w http.ResponseWriter, token string, maxAge time.Duration) {}
+
+func (t QueryTransport) ClearToken(w http.ResponseWriter) {}
+
+// WithTokenTransport replaces the session cookie with transports, in order of priority,
+// e.g. HeaderTransport, CookieTransport and QueryTransport for apps serving browsers and API clients.
+// The token is read from the first transport carrying one and written back to the same transport.
+// Tokens of new sessions are written to all of them.
+func WithTokenTransport(transports ...Transport) Option {
+	return func(s *SessionManager) {
+		s.transports = transports
+	}
+}
+
+// bindTransports defaults to the cookie transport and binds cookie transports to m.
+func (m *SessionManager) bindTransports() {
+	if len(m.transports) == 0 {
+		m.transports = []Transport{CookieTransport{}}
+	}
+	for i, transport := range m.transports {
+		if cookie, ok := transport.(CookieTransport); ok && cookie.m == nil {
+			m.transports[i] = CookieTransport{m: m}
+		}
+	}
+}
+
+// readToken returns the token sent with r by the transport with the highest priority.
+func (m *SessionManager) readToken(r *http.Request) (string, bool) {
+	token, transport := m.find(r)
+	return token, transport != nil
+}
+
+func (m *SessionManager) find(r *http.Request) (string, Transport) {
+	for _, transport := range m.transports {
+		if token, ok := transport.ReadToken(r); ok {
+			return token, transport
+		}
+	}
+	return "", nil
+}
+
+// responding returns the transports the response to r is sent with.
+func (m *SessionManager) responding(r *http.Request) []Transport {
+	if r != nil {
+		if _, transport := m.find(r); transport != nil {
+			return []Transport{transport}
+		}
+	}
+	return m.transports
+}
+
+func (m *SessionManager) writeToken(w http.ResponseWriter, r *http.Request, token string, maxAge time.Duration) {
+	for _, transport := range m.responding(r) {
+		if cookie, ok := transport.(CookieTransport); ok {
+			cookie.write(w, r, token, int(maxAge/time.Second))
+			continue
+		}
+		transport.WriteToken(w, token, maxAge)
+	}
+}
+
+func (m *SessionManager) clearToken(w http.ResponseWriter, r *http.Request) {
+	for _, transport := range m.responding(r) {
+		if cookie, ok := transport.(CookieTransport); ok {
+			cookie.write(w, r, "", -1)
+			continue
+		}
+		transport.ClearToken(w)
+	}
+}
+
+// Token returns the token of the request's session, e.g. to return it in the JSON body of a login response.
+// A session is only persisted once a value was put.
+func (m *SessionManager) Token(c *gin.Context) (string, error) {
+	return m.TokenContext(c.Request.Context())
+}
+
+// TokenContext is Token for the request context of Middleware.
+func (m *SessionManager) TokenContext(ctx context.Context) (string, error) {
+	session, ok := FromContext(ctx)
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+	return m.SessionToken(session)
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderTransport(t *testing.T) {
+	sm := NewSessionManager(WithTokenTransport(HeaderTransport{Name: "X-Session-Token"}))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		count, _ := GetGenericValue[int](sess, "count")
+		sess.Put("count", count+1)
+		c.JSON(http.StatusOK, count+1)
+	})
+	router.GET("/logout", func(c *gin.Context) {
+		assert.NoError(t, sm.Logout(c))
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	token := rw.Header().Get("X-Session-Token")
+	assert.NotEmpty(t, token)
+	assert.Empty(t, rw.Result().Cookies())
+
+	rw = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Session-Token", token)
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, "2", rw.Body.String())
+	assert.Equal(t, token, rw.Header().Get("X-Session-Token"))
+
+	rw = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/logout", nil)
+	req.Header.Set("X-Session-Token", token)
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, []string{""}, rw.Header().Values("X-Session-Token"))
+}
+
+func TestBearerTransport(t *testing.T) {
+	sm := NewSessionManager(WithTokenTransport(BearerTransport{}), WithSigningKey([]byte("secret")))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.POST("/login", func(c *gin.Context) {
+		assert.NoError(t, sm.Login(c, 42))
+		token, err := sm.Token(c)
+		assert.NoError(t, err)
+		c.JSON(http.StatusOK, gin.H{"token": token})
+	})
+	router.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, GetSession(c).Get(UserIDKey))
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/login", nil))
+	var body struct{ Token string }
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &body))
+	assert.NotEmpty(t, body.Token)
+	assert.Empty(t, rw.Result().Header.Values("Set-Cookie"))
+
+	rw = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+body.Token)
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, "42", rw.Body.String())
+
+	_, ok := BearerTransport{}.ReadToken(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, ok)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	_, ok = BearerTransport{}.ReadToken(req)
+	assert.False(t, ok)
+
+	rw = httptest.NewRecorder()
+	BearerTransport{ResponseHeader: "X-Session-Token"}.WriteToken(rw, "token", 0)
+	assert.Equal(t, "token", rw.Header().Get("X-Session-Token"))
+}
+
+func TestTransportChain(t *testing.T) {
+	sm := NewSessionManager(WithTokenTransport(
+		HeaderTransport{Name: "X-Session-Token"},
+		CookieTransport{},
+		QueryTransport{Name: "sid"},
+	))
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		count, _ := GetGenericValue[int](sess, "count")
+		sess.Put("count", count+1)
+		c.JSON(http.StatusOK, count+1)
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	token := rw.Header().Get("X-Session-Token")
+	assert.NotEmpty(t, token)
+	cookies := rw.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, token, cookies[0].Value)
+
+	// the header wins over the cookie and is the only one written back
+	rw = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Session-Token", token)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "other"})
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, "2", rw.Body.String())
+	assert.Equal(t, token, rw.Header().Get("X-Session-Token"))
+	assert.Empty(t, rw.Result().Cookies())
+
+	rw = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, "3", rw.Body.String())
+	assert.Empty(t, rw.Header().Get("X-Session-Token"))
+	assert.Len(t, rw.Result().Cookies(), 1)
+
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?sid="+token, nil))
+	assert.Equal(t, "4", rw.Body.String())
+	assert.Empty(t, rw.Header().Get("X-Session-Token"))
+	assert.Empty(t, rw.Result().Cookies())
+}
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PendingUserIDKey is the session key under which MarkPending2FA stores the user id until the second factor is verified.
+const PendingUserIDKey = "session.pending_user_id"
+
+// ErrNo2FAPending is returned by Complete2FA for sessions without a pending login.
+var ErrNo2FAPending = errors.New("no second factor pending")
+
+// MarkPending2FA records that the user passed the first factor. The session id is regenerated,
+// but the session is not authenticated until Complete2FA.
+func (m *SessionManager) MarkPending2FA(c *gin.Context, userID any) error {
+	err := m.MarkPending2FAContext(c.Request.Context(), userID)
+	if err != nil {
+		return err
+	}
+	session, _ := SessionFrom(c)
+	m.mirror(c, session)
+	return nil
+}
+
+// MarkPending2FAContext is MarkPending2FA for the request context of Middleware.
+func (m *SessionManager) MarkPending2FAContext(ctx context.Context, userID any) error {
+	session, err := m.RegenerateContext(ctx)
+	if err != nil {
+		return err
+	}
+	session.Put(PendingUserIDKey, userID)
+	return nil
+}
+
+// Complete2FA logs in the user of MarkPending2FA once the second factor was verified.
+func (m *SessionManager) Complete2FA(c *gin.Context) error {
+	err := m.Complete2FAContext(c.Request.Context())
+	if err != nil {
+		return err
+	}
+	session, _ := SessionFrom(c)
+	m.mirror(c, session)
+	return nil
+}
+
+// Complete2FAContext is Complete2FA for the request context of Middleware.
+func (m *SessionManager) Complete2FAContext(ctx context.Context) error {
+	session, ok := FromContext(ctx)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	userID, ok := session.Pending2FAUser()
+	if !ok {
+		return ErrNo2FAPending
+	}
+	err := m.LoginContext(ctx, userID)
+	if err != nil {
+		return err
+	}
+	session, _ = FromContext(ctx)
+	session.Delete(PendingUserIDKey)
+	return nil
+}
+
+// Pending2FAUser returns the user id of MarkPending2FA while the second factor is pending.
+func (s *Session) Pending2FAUser() (any, bool) {
+	return s.load(PendingUserIDKey)
+}
+
+// RequireFullAuth aborts requests with 401 Unauthorized unless their session was logged in
+// and has no second factor pending. It has to run after the session middleware.
+func RequireFullAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := SessionFrom(c)
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		_, loggedIn := session.load(UserIDKey)
+		_, pending := session.Pending2FAUser()
+		if !loggedIn || pending {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
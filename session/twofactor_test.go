@@ -0,0 +1,51 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPending2FA(t *testing.T) {
+	sm := NewSessionManager()
+	t.Cleanup(func() { sm.Close() })
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/password", func(c *gin.Context) {
+		assert.NoError(t, sm.MarkPending2FA(c, 42))
+	})
+	router.GET("/otp", func(c *gin.Context) {
+		assert.NoError(t, sm.Complete2FA(c))
+	})
+	router.GET("/account", RequireFullAuth(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, GetSession(c).Get(UserIDKey))
+	})
+
+	do := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do("/account", nil).Code)
+	pending := do("/password", nil).Result().Cookies()[0]
+	assert.Equal(t, http.StatusUnauthorized, do("/account", pending).Code)
+
+	full := do("/otp", pending).Result().Cookies()[0]
+	assert.NotEqual(t, pending.Value, full.Value)
+	rw := do("/account", full)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "42", rw.Body.String())
+
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	_, ok := sess.Pending2FAUser()
+	assert.False(t, ok)
+}
@@ -0,0 +1,347 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserIndex maps users to the store keys of their sessions. A store implementing UserIndex is used
+// as index of its manager unless WithUserIndex is set, so persistent stores can keep the index next
+// to the sessions. Otherwise an in-memory index is used, which only knows sessions bound by this process.
+type UserIndex interface {
+	AddUserSession(userID, key string) error
+	RemoveUserSession(userID, key string) error
+	UserSessions(userID string) ([]string, error)
+}
+
+func WithUserIndex(index UserIndex) Option {
+	return func(s *SessionManager) {
+		s.userIndex = index
+	}
+}
+
+type inMemoryUserIndex struct {
+	mu    sync.RWMutex
+	users map[string]map[string]struct{}
+}
+
+func NewInMemoryUserIndex() *inMemoryUserIndex {
+	return &inMemoryUserIndex{
+		users: make(map[string]map[string]struct{}),
+	}
+}
+
+func (i *inMemoryUserIndex) AddUserSession(userID, key string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	keys, ok := i.users[userID]
+	if !ok {
+		keys = make(map[string]struct{})
+		i.users[userID] = keys
+	}
+	keys[key] = struct{}{}
+	return nil
+}
+
+func (i *inMemoryUserIndex) RemoveUserSession(userID, key string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.users[userID], key)
+	if len(i.users[userID]) == 0 {
+		delete(i.users, userID)
+	}
+	return nil
+}
+
+func (i *inMemoryUserIndex) UserSessions(userID string) ([]string, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	keys := make([]string, 0, len(i.users[userID]))
+	for key := range i.users[userID] {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// useUserIndex defaults to the store as index, if it is one, or to an in-memory index.
+func (m *SessionManager) useUserIndex() {
+	if m.userIndex != nil {
+		return
+	}
+	if index, ok := m.store.(UserIndex); ok {
+		m.userIndex = index
+		return
+	}
+	m.userIndex = NewInMemoryUserIndex()
+}
+
+// userKey formats user ids of any type for the index.
+func userKey(userID any) string {
+	return fmt.Sprint(userID)
+}
+
+// SessionLimitPolicy decides what happens when a user exceeds WithMaxSessionsPerUser.
+type SessionLimitPolicy int
+
+const (
+	// LimitRejectNew fails the login or bind of another session with ErrTooManySessions. Remember-me
+	// logins are skipped instead, leaving the request anonymous.
+	LimitRejectNew SessionLimitPolicy = iota
+	// LimitEvictOldest destroys the oldest sessions of the user to make room for the new one.
+	LimitEvictOldest
+)
+
+// ErrTooManySessions is returned by Login and BindUser if the user already has the maximum number of sessions.
+var ErrTooManySessions = errors.New("too many sessions for user")
+
+// WithMaxSessionsPerUser limits the number of concurrent sessions of a user, enforced by Login, BindUser
+// and remember-me logins.
+func WithMaxSessionsPerUser(n int, policy SessionLimitPolicy) Option {
+	return func(s *SessionManager) {
+		s.maxSessionsPerUser = n
+		s.sessionLimitPolicy = policy
+	}
+}
+
+// admitUser makes sure the session of ctx can be bound to the user without exceeding the session limit.
+func (m *SessionManager) admitUser(ctx context.Context, userID any) error {
+	if m.maxSessionsPerUser <= 0 {
+		return nil
+	}
+	sessions, err := m.userSessions(userID)
+	if err != nil {
+		return err
+	}
+	if current, ok := FromContext(ctx); ok {
+		delete(sessions, current.storeKey())
+	}
+	if len(sessions) < m.maxSessionsPerUser {
+		return nil
+	}
+	if m.sessionLimitPolicy == LimitRejectNew {
+		return ErrTooManySessions
+	}
+
+	oldest := make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		oldest = append(oldest, session)
+	}
+	sort.Slice(oldest, func(i, j int) bool {
+		return oldest[i].createdAt.Before(oldest[j].createdAt)
+	})
+	for _, session := range oldest[:len(oldest)-m.maxSessionsPerUser+1] {
+		err = m.store.destroy(session.storeKey())
+		if err != nil {
+			return err
+		}
+		m.unindex(session)
+		m.hooks.destroy(session)
+		m.audit(AuditDestroyed, session, nil)
+	}
+	return nil
+}
+
+// BindUser stores userID under UserIDKey and adds the session to the index of the user,
+// without regenerating the session id like Login.
+func (m *SessionManager) BindUser(c *gin.Context, userID any) error {
+	return m.BindUserContext(c.Request.Context(), userID)
+}
+
+// BindUserContext is BindUser for the request context of Middleware.
+func (m *SessionManager) BindUserContext(ctx context.Context, userID any) error {
+	session, ok := FromContext(ctx)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	err := m.admitUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	m.unindex(session)
+	session.Put(UserIDKey, userID)
+	return m.userIndex.AddUserSession(userKey(userID), session.storeKey())
+}
+
+// DestroyAllForUser destroys every session of the user, e.g. after a password change or a ban.
+// If ctx belongs to a request of the user, its session is replaced by a new one as by DestroyContext.
+func (m *SessionManager) DestroyAllForUser(ctx context.Context, userID any) error {
+	return m.destroyUserSessions(ctx, userID, false)
+}
+
+// DestroyOtherSessions destroys every session of the user except the request's one,
+// e.g. for a "log out other devices" button.
+func (m *SessionManager) DestroyOtherSessions(c *gin.Context, userID any) error {
+	return m.DestroyOtherSessionsContext(c.Request.Context(), userID)
+}
+
+// DestroyOtherSessionsContext is DestroyOtherSessions for the request context of Middleware.
+func (m *SessionManager) DestroyOtherSessionsContext(ctx context.Context, userID any) error {
+	if _, ok := FromContext(ctx); !ok {
+		return ErrSessionNotFound
+	}
+	return m.destroyUserSessions(ctx, userID, true)
+}
+
+// destroyUserSessions destroys the sessions of the user, except for the session of ctx if keepCurrent is set.
+func (m *SessionManager) destroyUserSessions(ctx context.Context, userID any, keepCurrent bool) error {
+	keys, err := m.userIndex.UserSessions(userKey(userID))
+	if err != nil {
+		return err
+	}
+	current, _ := FromContext(ctx)
+
+	for _, key := range keys {
+		if current != nil && current.storeKey() == key {
+			if keepCurrent {
+				continue
+			}
+			err = m.DestroyContext(ctx)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		session, err := m.readKey(key, "")
+		if err != nil && !errors.Is(err, ErrCorruptSession) {
+			return err
+		}
+		err = m.store.destroy(key)
+		if err != nil {
+			return err
+		}
+		err = m.userIndex.RemoveUserSession(userKey(userID), key)
+		if err != nil {
+			return err
+		}
+		if session != nil {
+			m.hooks.destroy(session)
+			m.audit(AuditDestroyed, session, nil)
+		}
+	}
+	return nil
+}
+
+// unindex removes the session from the index of its user, if it has one.
+func (m *SessionManager) unindex(session *Session) {
+	userID, ok := indexedUser(session)
+	if !ok {
+		return
+	}
+	err := m.userIndex.RemoveUserSession(userKey(userID), session.storeKey())
+	if err != nil {
+		m.logger.Error("removing session from user index failed", "error", err)
+	}
+}
+
+// indexedUser returns the user the session is indexed under. Impersonating sessions stay with the impersonator.
+func indexedUser(session *Session) (any, bool) {
+	if userID, ok := session.load(ImpersonatorIDKey); ok {
+		return userID, true
+	}
+	return session.load(UserIDKey)
+}
+
+// SessionInfo describes a session for "your active sessions" pages. It never contains the session id.
+type SessionInfo struct {
+	// IDHash identifies the session for DestroyUserSession, it is the hex encoded SHA-256 of the id.
+	IDHash         string    `json:"id_hash"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	IP             string    `json:"ip,omitempty"`
+	UserAgent      string    `json:"user_agent,omitempty"`
+	// Current is set for the session of the request passed to SessionsForUser.
+	Current bool `json:"current"`
+}
+
+// SessionsForUser lists the active sessions of the user.
+func (m *SessionManager) SessionsForUser(ctx context.Context, userID any) ([]SessionInfo, error) {
+	sessions, err := m.userSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	current, _ := FromContext(ctx)
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for key, session := range sessions {
+		infos = append(infos, SessionInfo{
+			IDHash:         m.keyHash(key),
+			CreatedAt:      session.createdAt,
+			LastActivityAt: session.getLastActivity(),
+			IP:             session.ip,
+			UserAgent:      session.agent,
+			Current:        current != nil && current.storeKey() == key,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].LastActivityAt.After(infos[j].LastActivityAt)
+	})
+	return infos, nil
+}
+
+// DestroyUserSession destroys the session of the user with the IDHash of a SessionInfo,
+// so users can revoke single sessions. Sessions of other users are never destroyed.
+func (m *SessionManager) DestroyUserSession(ctx context.Context, userID any, idHash string) error {
+	sessions, err := m.userSessions(userID)
+	if err != nil {
+		return err
+	}
+	for key, session := range sessions {
+		if m.keyHash(key) != idHash {
+			continue
+		}
+		if current, ok := FromContext(ctx); ok && current.storeKey() == key {
+			return m.DestroyContext(ctx)
+		}
+		err = m.store.destroy(key)
+		if err != nil {
+			return err
+		}
+		m.unindex(session)
+		m.hooks.destroy(session)
+		m.audit(AuditDestroyed, session, nil)
+		return nil
+	}
+	return ErrUnknownSession
+}
+
+// userSessions reads the valid sessions of the user by store key and drops stale index entries.
+func (m *SessionManager) userSessions(userID any) (map[string]*Session, error) {
+	keys, err := m.userIndex.UserSessions(userKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	sessions := make(map[string]*Session, len(keys))
+	for _, key := range keys {
+		session, err := m.readKey(key, "")
+		if err != nil && !errors.Is(err, ErrCorruptSession) {
+			return nil, err
+		}
+		if session == nil || !m.validate(session) {
+			err = m.userIndex.RemoveUserSession(userKey(userID), key)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		sessions[key] = session
+	}
+	return sessions, nil
+}
+
+// keyHash returns the id hash of the session stored under key.
+func (m *SessionManager) keyHash(key string) string {
+	if m.hashIDs {
+		return key
+	}
+	return hashID(key)
+}
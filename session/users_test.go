@@ -0,0 +1,215 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newUserRouter returns a router whose /login/:user logs in and whose / counts requests.
+func newUserRouter(t *testing.T, sm *SessionManager) (*gin.Engine, func(path string, cookie *http.Cookie) *http.Cookie) {
+	router := gin.New()
+	router.Use(sm.Handle())
+	router.GET("/login/:user", func(c *gin.Context) {
+		assert.NoError(t, sm.Login(c, c.Param("user")))
+	})
+	router.GET("/", func(c *gin.Context) {
+		sess := GetSession(c)
+		count, _ := GetGenericValue[int](sess, "count")
+		sess.Put("count", count+1)
+	})
+
+	do := func(path string, cookie *http.Cookie) *http.Cookie {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(rw, req)
+		if cookies := rw.Result().Cookies(); len(cookies) > 0 {
+			return cookies[0]
+		}
+		return nil
+	}
+	return router, do
+}
+
+func TestDestroyAllForUser(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+	router, do := newUserRouter(t, sm)
+	router.GET("/password", func(c *gin.Context) {
+		assert.NoError(t, sm.DestroyAllForUser(c.Request.Context(), "alice"))
+	})
+
+	laptop := do("/login/alice", nil)
+	phone := do("/login/alice", nil)
+	bob := do("/login/bob", nil)
+
+	assert.Nil(t, do("/password", laptop))
+	assert.Nil(t, store.read(laptop.Value))
+	assert.Nil(t, store.read(phone.Value))
+	assert.NotNil(t, store.read(bob.Value))
+
+	keys, err := sm.userIndex.UserSessions("alice")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	assert.NoError(t, sm.DestroyAllForUser(context.Background(), "bob"))
+	assert.Nil(t, store.read(bob.Value))
+}
+
+func TestUserIndexFollowsSession(t *testing.T) {
+	sm := NewSessionManager()
+	t.Cleanup(func() { sm.Close() })
+	router, do := newUserRouter(t, sm)
+	router.GET("/logout", func(c *gin.Context) {
+		assert.NoError(t, sm.Logout(c))
+	})
+
+	cookie := do("/login/alice", nil)
+	cookie = do("/login/alice", cookie)
+	keys, _ := sm.userIndex.UserSessions("alice")
+	assert.Equal(t, []string{cookie.Value}, keys)
+
+	do("/logout", cookie)
+	keys, _ = sm.userIndex.UserSessions("alice")
+	assert.Empty(t, keys)
+}
+
+func TestDestroyOtherSessions(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+	router, do := newUserRouter(t, sm)
+	router.GET("/others", func(c *gin.Context) {
+		assert.NoError(t, sm.DestroyOtherSessions(c, "alice"))
+		GetSession(c).Put("kept", true)
+	})
+
+	laptop := do("/login/alice", nil)
+	phone := do("/login/alice", nil)
+	tablet := do("/login/alice", nil)
+
+	assert.Equal(t, laptop.Value, do("/others", laptop).Value)
+	assert.NotNil(t, store.read(laptop.Value))
+	assert.Nil(t, store.read(phone.Value))
+	assert.Nil(t, store.read(tablet.Value))
+
+	keys, _ := sm.userIndex.UserSessions("alice")
+	assert.Equal(t, []string{laptop.Value}, keys)
+}
+
+func TestSessionsForUser(t *testing.T) {
+	store := NewInMemorySessionStore()
+	sm := NewSessionManager(WithStore(store))
+	t.Cleanup(func() { sm.Close() })
+	router, do := newUserRouter(t, sm)
+	var infos []SessionInfo
+	router.GET("/sessions", func(c *gin.Context) {
+		var err error
+		infos, err = sm.SessionsForUser(c.Request.Context(), "alice")
+		assert.NoError(t, err)
+	})
+	router.GET("/revoke/:hash", func(c *gin.Context) {
+		assert.NoError(t, sm.DestroyUserSession(c.Request.Context(), "alice", c.Param("hash")))
+	})
+
+	laptop := do("/login/alice", nil)
+	phone := do("/login/alice", nil)
+	do("/login/bob", nil)
+
+	do("/sessions", laptop)
+	assert.Len(t, infos, 2)
+	var other SessionInfo
+	for _, info := range infos {
+		assert.Equal(t, "192.0.2.1", info.IP)
+		assert.NotContains(t, []string{laptop.Value, phone.Value}, info.IDHash)
+		if !info.Current {
+			other = info
+		}
+	}
+	assert.Equal(t, hashID(phone.Value), other.IDHash)
+
+	do("/revoke/"+other.IDHash, laptop)
+	assert.Nil(t, store.read(phone.Value))
+	assert.NotNil(t, store.read(laptop.Value))
+	assert.ErrorIs(t, sm.DestroyUserSession(context.Background(), "bob", hashID(laptop.Value)), ErrUnknownSession)
+	assert.NotNil(t, store.read(laptop.Value))
+}
+
+func TestMaxSessionsPerUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy SessionLimitPolicy
+	}{
+		{"reject new", LimitRejectNew},
+		{"evict oldest", LimitEvictOldest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewInMemorySessionStore()
+			sm := NewSessionManager(WithStore(store), WithMaxSessionsPerUser(2, tt.policy))
+			t.Cleanup(func() { sm.Close() })
+			var errs []error
+			router := gin.New()
+			router.Use(sm.Handle())
+			router.GET("/login", func(c *gin.Context) {
+				errs = append(errs, sm.Login(c, "alice"))
+			})
+			login := func() *http.Cookie {
+				rw := httptest.NewRecorder()
+				router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/login", nil))
+				if cookies := rw.Result().Cookies(); len(cookies) > 0 {
+					return cookies[0]
+				}
+				return nil
+			}
+
+			first, second := login(), login()
+			third := login()
+			assert.NoError(t, errs[0])
+			assert.NoError(t, errs[1])
+			assert.NotNil(t, store.read(second.Value))
+
+			if tt.policy == LimitRejectNew {
+				assert.ErrorIs(t, errs[2], ErrTooManySessions)
+				assert.Nil(t, third)
+				assert.NotNil(t, store.read(first.Value))
+			} else {
+				assert.NoError(t, errs[2])
+				assert.Nil(t, store.read(first.Value))
+				assert.NotNil(t, store.read(third.Value))
+			}
+		})
+	}
+}
+
+func TestUserSessionsQuarantinesCorrupt(t *testing.T) {
+	inner := NewInMemorySessionStore()
+	store, err := NewEncryptedStoreFromKeys(inner, StaticKeys([]byte("0123456789abcdef")))
+	assert.NoError(t, err)
+	metrics := NewMetrics()
+	sm := NewSessionManager(WithStore(store), WithMetrics(metrics))
+	t.Cleanup(func() { sm.Close() })
+	_, do := newUserRouter(t, sm)
+
+	laptop := do("/login/alice", nil)
+	phone := do("/login/alice", nil)
+	assert.NoError(t, inner.write(inner.read(phone.Value).withData(map[string]any{encryptedDataKey: "garbage"})))
+
+	infos, err := sm.SessionsForUser(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, hashID(laptop.Value), infos[0].IDHash)
+	assert.Nil(t, inner.read(phone.Value))
+	assert.Equal(t, uint64(1), metrics.corrupted.Load())
+	keys, err := sm.userIndex.UserSessions("alice")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
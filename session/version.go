@@ -0,0 +1,128 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict is returned by a store when the session was saved by another request
+// since it was loaded. The manager then merges the changes into the stored session and retries.
+var ErrVersionConflict = errors.New("session was modified concurrently")
+
+// MergeFunc applies the changes of attempted, the session a request failed to save,
+// onto current, the latest version in the store. current is saved afterwards.
+type MergeFunc func(current, attempted *Session) error
+
+// MergeChanges is the default MergeFunc. Keys put or deleted by the attempted session
+// overwrite the stored ones, all other keys keep their stored values.
+func MergeChanges(current, attempted *Session) error {
+	puts, deletes := attempted.changes()
+	for key, value := range puts {
+		current.Put(key, value)
+	}
+	for _, key := range deletes {
+		current.Delete(key)
+	}
+	return nil
+}
+
+// WithConflictRetries sets how often a save is retried after an ErrVersionConflict. Defaults to 3,
+// 0 passes conflicts straight to the error handler.
+func WithConflictRetries(retries int) Option {
+	return func(s *SessionManager) {
+		if retries < 0 {
+			panic(errors.New("conflict retries cannot be negative"))
+		}
+		s.conflictRetries = retries
+	}
+}
+
+// WithMergeFunc replaces MergeChanges as the conflict resolution.
+func WithMergeFunc(merge MergeFunc) Option {
+	return func(s *SessionManager) {
+		s.merge = merge
+	}
+}
+
+// changes returns the values of the keys put and the keys deleted since the session was last saved.
+func (s *Session) changes() (map[string]any, []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	puts := make(map[string]any, len(s.changed))
+	var deletes []string
+	for key := range s.changed {
+		if value, ok := s.snapshot()[key]; ok {
+			puts[key] = value
+		} else {
+			deletes = append(deletes, key)
+		}
+	}
+	return puts, deletes
+}
+
+// saved bumps the version after a successful write and forgets the changed keys.
+func (s *Session) saved() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	s.changed = nil
+	s.base = s.data.Load()
+}
+
+// rollback restores the keys changed since the last save to their saved values.
+func (s *Session) rollback() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var base map[string]any
+	if s.base != nil {
+		base = *s.base
+	}
+	s.mutate(func(data map[string]any) {
+		for key := range s.changed {
+			value, ok := base[key]
+			if !ok {
+				delete(data, key)
+				s.keys = removeKey(s.keys, key)
+				continue
+			}
+			if _, ok := data[key]; !ok {
+				s.keys = append(s.keys, key)
+			}
+			data[key] = value
+		}
+	})
+	s.changed = nil
+}
+
+// queue marks the session as stored when a store queued its write, so saving it again before the
+// write is not taken for its creation.
+func (s *Session) queue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queued = true
+}
+
+// isStored reports whether the session was written to the store or queued for writing.
+func (s *Session) isStored() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.version > 0 || s.queued
+}
+
+func (m *SessionManager) resolveConflict(attempted *Session) (*Session, error) {
+	current := m.read(attempted.id)
+	if current == nil {
+		return nil, fmt.Errorf("%w: session was destroyed", ErrVersionConflict)
+	}
+
+	err := m.merge(current, attempted)
+	if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
@@ -0,0 +1,51 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionConflictMerge(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "sessions.json"))
+	sm := NewSessionManager(WithStore(store))
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("a", "initial")
+	sess.Put("b", "initial")
+	assert.NoError(t, sm.save(sess, nil))
+	assert.Equal(t, uint64(1), sess.Version())
+
+	first := store.read(sess.id)
+	second := store.read(sess.id)
+	first.Put("a", "first")
+	second.Delete("b")
+	second.Put("c", "second")
+
+	assert.NoError(t, sm.save(first, nil))
+	assert.NoError(t, sm.save(second, nil))
+
+	stored := store.read(sess.id)
+	assert.Equal(t, uint64(3), stored.Version())
+	assert.Equal(t, "first", stored.Get("a"))
+	assert.Nil(t, stored.Get("b"))
+	assert.Equal(t, "second", stored.Get("c"))
+}
+
+func TestVersionConflictWithoutRetries(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "sessions.json"))
+	sm := NewSessionManager(WithStore(store), WithConflictRetries(0))
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("a", "initial")
+	assert.NoError(t, sm.save(sess, nil))
+
+	first := store.read(sess.id)
+	second := store.read(sess.id)
+	first.Put("a", "first")
+	second.Put("a", "second")
+
+	assert.NoError(t, sm.save(first, nil))
+	assert.ErrorIs(t, sm.save(second, nil), ErrVersionConflict)
+}
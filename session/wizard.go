@@ -0,0 +1,100 @@
+package session
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wizardKeyPrefix prefixes the session keys of wizards, followed by the wizard name.
+const wizardKeyPrefix = "wizard."
+
+// Wizard tracks a multi-step flow such as a checkout or an onboarding in the session.
+// All of its state is kept under session keys prefixed with "wizard.<name>.".
+type Wizard struct {
+	session *Session
+	prefix  string
+}
+
+// Wizard returns the wizard called name. It is inactive until Start is called.
+func (s *Session) Wizard(name string) *Wizard {
+	return &Wizard{session: s, prefix: wizardKeyPrefix + name + "."}
+}
+
+// Start begins the wizard at step, discarding a previous run. The wizard is cleared if it is not finished within ttl.
+func (w *Wizard) Start(step string, ttl time.Duration) {
+	w.clear()
+	w.session.Put(w.prefix+"expires", strconv.FormatInt(w.session.now().Add(ttl).UnixNano(), 10))
+	w.session.Put(w.prefix+"step", step)
+}
+
+// Active reports whether the wizard was started and has neither finished nor expired.
+func (w *Wizard) Active() bool {
+	stored, ok := w.session.load(w.prefix + "expires")
+	if !ok {
+		return false
+	}
+	expires, _ := stored.(string)
+	nanos, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || w.session.now().UnixNano() > nanos {
+		w.clear()
+		return false
+	}
+	return true
+}
+
+// Step returns the current step, or an empty string if the wizard is not active.
+func (w *Wizard) Step() string {
+	if !w.Active() {
+		return ""
+	}
+	stored, _ := w.session.load(w.prefix + "step")
+	step, ok := stored.(string)
+	if !ok {
+		w.clear()
+	}
+	return step
+}
+
+// Complete stores the payload of step and moves on to next. It reports false if the wizard is not active.
+func (w *Wizard) Complete(step string, payload any, next string) bool {
+	if !w.Active() {
+		return false
+	}
+	w.session.Put(w.prefix+"data."+step, payload)
+	w.session.Put(w.prefix+"step", next)
+	return true
+}
+
+// Payload returns the payload stored by Complete for step.
+func (w *Wizard) Payload(step string) (any, bool) {
+	if !w.Active() {
+		return nil, false
+	}
+	return w.session.load(w.prefix + "data." + step)
+}
+
+// Finish returns the payloads of all completed steps and clears the wizard.
+// It returns nil if the wizard is not active.
+func (w *Wizard) Finish() map[string]any {
+	if !w.Active() {
+		return nil
+	}
+	payloads := make(map[string]any)
+	for _, key := range w.session.Keys() {
+		if step, ok := strings.CutPrefix(key, w.prefix+"data."); ok {
+			payloads[step], _ = w.session.load(key)
+		}
+	}
+	w.clear()
+	return payloads
+}
+
+// clear deletes all keys of the wizard.
+func (w *Wizard) clear() {
+	for _, key := range w.session.Keys() {
+		if strings.HasPrefix(key, w.prefix) {
+			w.session.Delete(key)
+		}
+	}
+}
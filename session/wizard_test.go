@@ -0,0 +1,51 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWizard(t *testing.T) {
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	sess.Put("cart", 3)
+
+	checkout := sess.Wizard("checkout")
+	assert.False(t, checkout.Active())
+	assert.False(t, checkout.Complete("address", "Main St", "payment"))
+
+	checkout.Start("address", time.Minute)
+	assert.Equal(t, "address", checkout.Step())
+	assert.True(t, checkout.Complete("address", "Main St", "payment"))
+	assert.Equal(t, "payment", checkout.Step())
+	payload, ok := checkout.Payload("address")
+	assert.True(t, ok)
+	assert.Equal(t, "Main St", payload)
+
+	onboarding := sess.Wizard("onboarding")
+	onboarding.Start("profile", -time.Second)
+	assert.False(t, onboarding.Active())
+	assert.Empty(t, onboarding.Step())
+
+	assert.True(t, checkout.Complete("payment", "card", "review"))
+	assert.Equal(t, map[string]any{"address": "Main St", "payment": "card"}, checkout.Finish())
+	assert.False(t, checkout.Active())
+	assert.Nil(t, checkout.Finish())
+	assert.Equal(t, []string{"cart"}, sess.Keys())
+}
+
+func TestWizardForeignValues(t *testing.T) {
+	sess, err := newSession(generateSessionID)
+	assert.NoError(t, err)
+	wizard := sess.Wizard("checkout")
+
+	sess.Put("wizard.checkout.expires", float64(time.Now().Add(time.Hour).UnixNano()))
+	assert.False(t, wizard.Active())
+
+	wizard.Start("address", time.Hour)
+	sess.Put("wizard.checkout.step", 2)
+	assert.Empty(t, wizard.Step())
+	assert.False(t, wizard.Active())
+}